@@ -9,7 +9,7 @@ go install github.com/akavel/rsrc@latest
 if ($LASTEXITCODE -ne 0) { exit 1 }
 
 Write-Host "Embedding manifest + icon..." -ForegroundColor Cyan
-rsrc -manifest image-uploader.manifest -ico favicon.ico -o rsrc.syso
+rsrc -manifest image-uploader.manifest -ico favicon.ico -o rsrc_windows_amd64.syso
 if ($LASTEXITCODE -ne 0) { exit 1 }
 
 Write-Host "Building executable..." -ForegroundColor Cyan
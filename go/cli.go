@@ -0,0 +1,1177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	exitOK        = 0
+	exitFailure   = 1
+	exitUsage     = 2
+	exitCancelled = 130
+)
+
+const cliUsage = `Usage:
+  image-uploader                          start the GUI
+  image-uploader upload [flags] FILE...   upload files without the GUI
+  image-uploader history [flags] [TEXT]   search past uploads
+  image-uploader album ACTION SHORT ...   manage a catbox album (edit, add, remove, delete)
+  image-uploader delete [flags] URL...    delete uploaded files
+  image-uploader shorten URL...           shorten links with sxcu
+  image-uploader post ACTION ID           show, delete or favorite an imgchest post
+  image-uploader file ACTION ID           show, describe or delete one imgchest image
+  image-uploader posts [flags]            list the posts of your imgchest account
+  image-uploader kek ACTION [ID...]       list, delete or change your kek posts
+  image-uploader help                     show this help
+
+Run "image-uploader COMMAND -h" for the flags of a command.
+
+Exit status is 0 when every upload succeeded, 1 when any upload failed,
+2 on invalid usage and 130 when interrupted with Ctrl+C.
+
+Uploads are recorded in history.jsonl under the user config directory;
+set IMAGE_UPLOADER_HISTORY to use a different file. sxcu embed presets are
+kept in sxcu-embed-presets.json in the same directory (IMAGE_UPLOADER_OG_PRESETS).
+`
+
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, cliUsage)
+		return exitUsage
+	}
+
+	if command, ok := cliCommands[args[0]]; ok {
+		return command(args[1:], stdout, stderr)
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], cliUsage)
+		return exitUsage
+	}
+}
+
+var cliCommands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"upload":  runUploadCommand,
+	"history": runHistoryCommand,
+	"album":   runAlbumCommand,
+	"delete":  runDeleteCommand,
+	"shorten": runShortenCommand,
+	"post":    runPostCommand,
+	"file":    runFileCommand,
+	"posts":   runPostsCommand,
+	"kek":     runKekCommand,
+}
+
+// isCLIInvocation reports whether args name a subcommand or ask for help.
+// Anything else, such as files passed by "Open with" or dropped onto the
+// executable, starts the GUI instead.
+func isCLIInvocation(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if _, ok := cliCommands[args[0]]; ok {
+		return true
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		consumed := len(args) - fs.NArg()
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, fs.Args()...), nil
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func splitURLList(urls string) []string {
+	values := make([]string, 0, 4)
+	for _, u := range strings.Split(urls, ",") {
+		u = strings.TrimSpace(u)
+		if u != "" {
+			values = append(values, u)
+		}
+	}
+	return values
+}
+
+// readImageDescriptions parses a descriptions file and keys each entry by the
+// upload path it names, matching either the path as given or its base name.
+func readImageDescriptions(path string, files []string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptions: %w", err)
+	}
+	byName := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, desc, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected FILE<TAB>DESCRIPTION", path, i+1)
+		}
+		byName[strings.TrimSpace(name)] = strings.TrimSpace(desc)
+	}
+	descriptions := make(map[string]string, len(byName))
+	for _, file := range files {
+		if desc, ok := byName[file]; ok {
+			descriptions[file] = desc
+		} else if desc, ok := byName[filepath.Base(file)]; ok {
+			descriptions[file] = desc
+		}
+	}
+	return descriptions, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+type cliReporter struct {
+	mu            sync.Mutex
+	w             io.Writer
+	live          bool
+	lastStatus    string
+	progressWidth int
+}
+
+func newCLIReporter(w io.Writer) *cliReporter {
+	r := &cliReporter{w: w}
+	if f, ok := w.(*os.File); ok {
+		if st, err := f.Stat(); err == nil {
+			r.live = st.Mode()&os.ModeCharDevice != 0
+		}
+	}
+	return r
+}
+
+func (r *cliReporter) clearProgress() {
+	if r.progressWidth > 0 {
+		fmt.Fprintf(r.w, "\r%s\r", strings.Repeat(" ", r.progressWidth))
+		r.progressWidth = 0
+	}
+}
+
+func (r *cliReporter) Status(text string) {
+	status, _, _ := strings.Cut(text, "\r\n")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if status == r.lastStatus {
+		return
+	}
+	r.lastStatus = status
+	r.clearProgress()
+	fmt.Fprintln(r.w, status)
+}
+
+func (r *cliReporter) Progress(p UploadProgress) {
+	if !r.live {
+		return
+	}
+	line := formatProgress(p)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	width := len([]rune(line))
+	pad := ""
+	if r.progressWidth > width {
+		pad = strings.Repeat(" ", r.progressWidth-width)
+	}
+	fmt.Fprintf(r.w, "\r%s%s", line, pad)
+	r.progressWidth = width
+}
+
+func (r *cliReporter) Finish() {
+	r.mu.Lock()
+	r.clearProgress()
+	r.mu.Unlock()
+}
+
+func runUploadCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	provider := fs.String("provider", "catbox", "upload provider: "+strings.Join(ProviderNames(), ", "))
+	title := fs.String("title", "", "album, collection or post title")
+	desc := fs.String("desc", "", "album or collection description")
+	urls := fs.String("urls", "", "comma-separated URLs to upload (catbox, kek)")
+	album := fs.Bool("album", false, "create a catbox album from the uploads")
+	albumShort := fs.String("album-short", "", "add the uploads to an existing catbox album (short code or URL)")
+	expiry := fs.String("expiry", "", "upload to Litterbox instead of catbox, expiring after 1h, 12h, 24h or 72h")
+	collection := fs.Bool("collection", false, "create an sxcu collection for the uploads")
+	private := fs.Bool("private", false, "make the sxcu collection private")
+	collectionID := fs.String("collection-id", "", "add to an existing sxcu collection (ID or URL)")
+	collectionToken := fs.String("collection-token", "", "token of the existing sxcu collection (default: remembered from history)")
+	selfDestruct := fs.Bool("self-destruct", false, "delete the sxcu uploads automatically after 24 hours")
+	paste := fs.Bool("paste", false, "upload the files as cancer-co.de text pastes (sxcu)")
+	subdomain := fs.String("subdomain", "", "upload to this sxcu subdomain (default: $SXCU_SUBDOMAIN or sxcu.txt)")
+	ogPreset := fs.String("og-preset", "", "start from a saved sxcu embed preset")
+	ogTitle := fs.String("og-title", "", `sxcu embed title ("false" omits it)`)
+	ogDesc := fs.String("og-desc", "", `sxcu embed description ("false" omits it)`)
+	ogColor := fs.String("og-color", "", `sxcu embed color such as #7289DA ("false" omits it)`)
+	ogSiteName := fs.String("og-site-name", "", `sxcu embed site name ("false" omits it)`)
+	ogShowURL := fs.Bool("og-show-url", false, "stop Discord from hiding the sxcu link")
+	saveOGPreset := fs.String("save-og-preset", "", "save the sxcu embed options as a preset with this name")
+	uploadToken := fs.String("upload-token", "", "sxcu subdomain upload token (default: $SXCU_UPLOAD_TOKEN or sxcu.txt)")
+	postID := fs.String("post-id", "", "add to an existing imgchest post")
+	privacy := fs.String("privacy", "hidden", "imgchest post privacy: hidden, public, secret")
+	nsfw := fs.Bool("nsfw", false, "mark the imgchest post as NSFW")
+	anonymous := fs.Bool("anonymous", false, "create an anonymous imgchest post")
+	descriptions := fs.String("descriptions", "", "imgchest image descriptions, one \"FILE<TAB>DESCRIPTION\" per line (sidecar FILE.txt is used otherwise)")
+	userhash := fs.String("userhash", "", "catbox userhash (default: $CATBOX_USERHASH or catbox.txt)")
+	token := fs.String("token", "", "imgchest API token (default: $IMGCHEST_TOKEN or imgchest.txt)")
+	apiKey := fs.String("api-key", "", "kek API key (default: $KEK_API_KEY or kek.txt)")
+	mature := fs.Bool("mature", false, "mark kek posts as mature")
+	visibility := fs.String("visibility", "", "make kek posts public or private (default: account setting; private depends on your plan)")
+	quiet := fs.Bool("quiet", false, "do not print progress to stderr")
+
+	files, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+
+	p, ok := LookupProvider(*provider)
+	if !ok {
+		fmt.Fprintf(stderr, "unknown provider %q (expected %s)\n", *provider, strings.Join(ProviderNames(), ", "))
+		return exitUsage
+	}
+	urlValues := splitURLList(*urls)
+	if len(urlValues) > 0 && !p.Capabilities().URLUpload {
+		fmt.Fprintf(stderr, "URL uploads are not supported by %s\n", p.Name())
+		return exitUsage
+	}
+
+	if len(files) == 0 && len(urlValues) == 0 {
+		fmt.Fprintln(stderr, "no files or URLs to upload")
+		fs.Usage()
+		return exitUsage
+	}
+
+	imgchestPrivacy := strings.ToLower(strings.TrimSpace(*privacy))
+	switch imgchestPrivacy {
+	case "hidden", "public", "secret":
+	default:
+		fmt.Fprintf(stderr, "invalid privacy %q (expected hidden, public or secret)\n", *privacy)
+		return exitUsage
+	}
+	litterboxExpiry := strings.ToLower(strings.TrimSpace(*expiry))
+	if litterboxExpiry != "" {
+		if _, ok := litterboxExpiries[litterboxExpiry]; !ok {
+			fmt.Fprintf(stderr, "invalid expiry %q (expected 1h, 12h, 24h or 72h)\n", *expiry)
+			return exitUsage
+		}
+		if p.Name() != "catbox" {
+			fmt.Fprintln(stderr, "-expiry is only supported by catbox")
+			return exitUsage
+		}
+		if len(urlValues) > 0 {
+			fmt.Fprintln(stderr, "Litterbox does not support URL uploads")
+			return exitUsage
+		}
+		if *album || *albumShort != "" {
+			fmt.Fprintln(stderr, "Litterbox uploads cannot be added to albums")
+			return exitUsage
+		}
+	}
+	if (*subdomain != "" || *uploadToken != "") && p.Name() != "sxcu" {
+		fmt.Fprintln(stderr, "-subdomain and -upload-token are only supported by sxcu")
+		return exitUsage
+	}
+	if (*collectionID != "" || *collectionToken != "") && p.Name() != "sxcu" {
+		fmt.Fprintln(stderr, "-collection-id and -collection-token are only supported by sxcu")
+		return exitUsage
+	}
+	if *collection && *collectionID != "" {
+		fmt.Fprintln(stderr, "-collection and -collection-id cannot be combined")
+		return exitUsage
+	}
+	if *selfDestruct && p.Name() != "sxcu" {
+		fmt.Fprintln(stderr, "-self-destruct is only supported by sxcu; use -expiry for catbox")
+		return exitUsage
+	}
+	if *paste && p.Name() != "sxcu" {
+		fmt.Fprintln(stderr, "-paste is only supported by sxcu")
+		return exitUsage
+	}
+	if *paste && (*collection || *collectionID != "" || *selfDestruct || *subdomain != "") {
+		fmt.Fprintln(stderr, "-paste cannot be combined with -collection, -collection-id, -self-destruct or -subdomain")
+		return exitUsage
+	}
+	og := SxcuOGProperties{
+		Title:       strings.TrimSpace(*ogTitle),
+		Description: strings.TrimSpace(*ogDesc),
+		Color:       strings.TrimSpace(*ogColor),
+		SiteName:    strings.TrimSpace(*ogSiteName),
+		ShowURL:     *ogShowURL,
+	}
+	if (*ogPreset != "" || *saveOGPreset != "" || !og.IsZero()) && p.Name() != "sxcu" {
+		fmt.Fprintln(stderr, "embed options are only supported by sxcu")
+		return exitUsage
+	}
+	if *ogPreset != "" || *saveOGPreset != "" {
+		presets, err := OpenOGPresetStore()
+		if err == nil && *ogPreset != "" {
+			var preset SxcuOGProperties
+			if preset, err = presets.Get(*ogPreset); err == nil {
+				og = preset.Merge(og)
+			}
+		}
+		if err == nil && *saveOGPreset != "" {
+			err = presets.Save(*saveOGPreset, og)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return exitUsage
+		}
+	}
+	if err := og.Validate(); err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	}
+	if *paste && !og.IsZero() {
+		fmt.Fprintln(stderr, "embed options do not apply to pastes")
+		return exitUsage
+	}
+	if *album && *albumShort != "" {
+		fmt.Fprintln(stderr, "-album and -album-short cannot be combined")
+		return exitUsage
+	}
+	kekVisibility := strings.ToLower(strings.TrimSpace(*visibility))
+	switch kekVisibility {
+	case "", "public", "private":
+	default:
+		fmt.Fprintf(stderr, "invalid visibility %q (expected public or private)\n", *visibility)
+		return exitUsage
+	}
+	if kekVisibility != "" && p.Name() != "kek" {
+		fmt.Fprintln(stderr, "-visibility is only supported by kek")
+		return exitUsage
+	}
+	if *anonymous && *postID != "" {
+		fmt.Fprintln(stderr, "anonymous uploads cannot be added to an existing post")
+		return exitUsage
+	}
+	var imageDescriptions map[string]string
+	if *descriptions != "" {
+		if p.Name() != "imgchest" {
+			fmt.Fprintln(stderr, "-descriptions is only supported by imgchest")
+			return exitUsage
+		}
+		if imageDescriptions, err = readImageDescriptions(*descriptions, files); err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return exitUsage
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	acquired, err := TryAcquireUploadLock()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to acquire upload lock: %v\n", err)
+		return exitFailure
+	}
+	if !acquired {
+		if !*quiet {
+			fmt.Fprintln(stderr, "Waiting for another upload to complete...")
+		}
+		if err := AcquireUploadLock(ctx); err != nil {
+			if ctx.Err() != nil {
+				fmt.Fprintln(stderr, "Cancelled")
+				return exitCancelled
+			}
+			fmt.Fprintf(stderr, "Failed to acquire lock: %v\n", err)
+			return exitFailure
+		}
+	}
+	defer ReleaseUploadLock()
+
+	SetCatboxUserhash(firstNonEmpty(*userhash, os.Getenv("CATBOX_USERHASH")))
+	SetImgchestToken(firstNonEmpty(*token, os.Getenv("IMGCHEST_TOKEN")))
+	SetKekAPIKey(firstNonEmpty(*apiKey, os.Getenv("KEK_API_KEY")))
+	SetSxcuSubdomain(firstNonEmpty(*subdomain, os.Getenv("SXCU_SUBDOMAIN")), firstNonEmpty(*uploadToken, os.Getenv("SXCU_UPLOAD_TOKEN")))
+
+	job := &UploadJob{
+		Files:               files,
+		URLs:                urlValues,
+		Title:               *title,
+		Desc:                *desc,
+		CreateAlbum:         *album,
+		AlbumShort:          catboxAlbumShort(*albumShort),
+		LitterboxExpiry:     litterboxExpiry,
+		CreateCollection:    *collection,
+		SxcuCollectionID:    sxcuCollectionID(*collectionID),
+		SxcuCollectionToken: strings.TrimSpace(*collectionToken),
+		SxcuCollection: SxcuCollectionOptions{
+			Private:  *private,
+			Unlisted: true,
+		},
+		SxcuOG:           og,
+		SxcuSelfDestruct: *selfDestruct,
+		SxcuPaste:        *paste,
+		Imgchest: ImgchestUploadOptions{
+			Privacy:   imgchestPrivacy,
+			NSFW:      *nsfw,
+			Anonymous: *anonymous,
+		},
+		ImageDescriptions: imageDescriptions,
+		PostID:            strings.TrimSpace(*postID),
+		KekMature:         *mature,
+		KekVisibility:     kekVisibility,
+	}
+
+	reporter := newCLIReporter(stderr)
+	updateOutput := func(string) {}
+	if !*quiet {
+		updateOutput = reporter.Status
+		ctx = withProgressTracker(ctx, newProgressTracker(totalFileSize(job.Files), reporter.Progress))
+	}
+	summary := p.Upload(ctx, job, updateOutput)
+	reporter.Finish()
+	if err := recordUploadHistory(p.Name(), job, summary); err != nil {
+		fmt.Fprintf(stderr, "warning: failed to record history: %v\n", err)
+	}
+
+	if summary.GroupResult != "" {
+		fmt.Fprintln(stdout, summary.GroupResult)
+	}
+	for _, r := range summary.Results {
+		fmt.Fprintln(stdout, r)
+	}
+	if !*quiet {
+		for _, d := range summary.Details() {
+			fmt.Fprintln(stderr, d)
+		}
+	}
+	for _, e := range summary.Errors {
+		fmt.Fprintf(stderr, "error: %s\n", e)
+	}
+
+	if summary.Cancelled {
+		if !*quiet {
+			fmt.Fprintf(stderr, "Cancelled: %d uploaded before cancellation\n", summary.SuccessCount)
+		}
+		return exitCancelled
+	}
+	if len(summary.Errors) > 0 {
+		if !*quiet {
+			fmt.Fprintf(stderr, "Done: %d success, %d failed\n", summary.SuccessCount, len(summary.Errors))
+		}
+		return exitFailure
+	}
+	if !*quiet {
+		fmt.Fprintf(stderr, "Done: %d uploaded\n", summary.SuccessCount)
+	}
+	return exitOK
+}
+
+func parseHistoryDate(value string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", strings.TrimSpace(value), time.Local)
+}
+
+func runHistoryCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	provider := fs.String("provider", "", "only show uploads to this provider")
+	date := fs.String("date", "", "only show uploads from this day (YYYY-MM-DD)")
+	since := fs.String("since", "", "only show uploads on or after this day (YYYY-MM-DD)")
+	until := fs.String("until", "", "only show uploads on or before this day (YYYY-MM-DD)")
+	limit := fs.Int("limit", 50, "maximum number of entries to show (0 for all)")
+	links := fs.Bool("links", false, "print only the links, one per line")
+
+	terms, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+
+	q := HistoryQuery{Text: strings.Join(terms, " "), Limit: *limit}
+	if *provider != "" {
+		p, ok := LookupProvider(*provider)
+		if !ok {
+			fmt.Fprintf(stderr, "unknown provider %q (expected %s)\n", *provider, strings.Join(ProviderNames(), ", "))
+			return exitUsage
+		}
+		q.Provider = p.Name()
+	}
+	if *date != "" {
+		*since, *until = *date, *date
+	}
+	if *since != "" {
+		if q.Since, err = parseHistoryDate(*since); err != nil {
+			fmt.Fprintf(stderr, "invalid date %q (expected YYYY-MM-DD)\n", *since)
+			return exitUsage
+		}
+	}
+	if *until != "" {
+		day, err := parseHistoryDate(*until)
+		if err != nil {
+			fmt.Fprintf(stderr, "invalid date %q (expected YYYY-MM-DD)\n", *until)
+			return exitUsage
+		}
+		q.Until = day.AddDate(0, 0, 1)
+	}
+
+	store, err := OpenHistoryStore()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open history: %v\n", err)
+		return exitFailure
+	}
+	entries, err := store.Search(q)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitFailure
+	}
+
+	if *links {
+		for _, e := range entries {
+			fmt.Fprintln(stdout, e.URL)
+		}
+		return exitOK
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(stderr, "No uploads found")
+		return exitOK
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tPROVIDER\tNAME\tURL\tGROUP\tSTATUS")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.UploadedAt.Local().Format("2006-01-02 15:04"), e.Provider, e.Name(), e.URL, e.GroupURL, e.Status())
+	}
+	tw.Flush()
+	return exitOK
+}
+
+const albumUsage = `Usage:
+  image-uploader album edit [flags] SHORT FILE...   replace title, description and files
+  image-uploader album add [flags] SHORT FILE...    add files to the album
+  image-uploader album remove [flags] SHORT FILE... remove files from the album
+  image-uploader album delete [flags] SHORT         delete the album
+
+SHORT is the album short code or URL. FILE is a catbox file name or URL.
+edit requires -title and -desc, since catbox clears any field left out.
+Album management requires a catbox userhash.
+`
+
+func runAlbumCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, albumUsage)
+		return exitUsage
+	}
+	action := args[0]
+	switch action {
+	case "edit", "add", "remove", "delete":
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, albumUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown album action %q\n\n%s", action, albumUsage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("album "+action, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	title := fs.String("title", "", "album title (required for edit)")
+	desc := fs.String("desc", "", "album description (required for edit)")
+	userhash := fs.String("userhash", "", "catbox userhash (default: $CATBOX_USERHASH or catbox.txt)")
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) == 0 {
+		fmt.Fprintln(stderr, "missing album short code")
+		return exitUsage
+	}
+	short := catboxAlbumShort(positional[0])
+	fileNames := make([]string, 0, len(positional)-1)
+	for _, f := range positional[1:] {
+		fileNames = append(fileNames, extractCatboxFilename(strings.TrimSpace(f)))
+	}
+	if action == "delete" && len(fileNames) > 0 {
+		fmt.Fprintln(stderr, "album delete takes no files")
+		return exitUsage
+	}
+	if action == "edit" {
+		set := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["title"] || !set["desc"] {
+			fmt.Fprintln(stderr, `album edit replaces the title and description; pass both -title and -desc (use "" to clear one)`)
+			return exitUsage
+		}
+	}
+	if action != "delete" && len(fileNames) == 0 {
+		if action == "edit" {
+			fmt.Fprintln(stderr, "album edit replaces the album's files; list every file to keep")
+		} else {
+			fmt.Fprintln(stderr, "no files given")
+		}
+		return exitUsage
+	}
+
+	SetCatboxUserhash(firstNonEmpty(*userhash, os.Getenv("CATBOX_USERHASH")))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var albumURL string
+	switch action {
+	case "edit":
+		albumURL, err = editCatboxAlbum(ctx, short, *title, *desc, fileNames)
+	case "add":
+		albumURL, err = addToCatboxAlbum(ctx, short, fileNames)
+	case "remove":
+		albumURL, err = removeFromCatboxAlbum(ctx, short, fileNames)
+	case "delete":
+		err = deleteCatboxAlbum(ctx, short)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "Cancelled")
+			return exitCancelled
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	if action == "delete" {
+		fmt.Fprintf(stderr, "Deleted album %s\n", short)
+	} else {
+		fmt.Fprintln(stdout, albumURL)
+	}
+	return exitOK
+}
+
+const postUsage = `Usage:
+  image-uploader post show [flags] ID       show the post's details and images
+  image-uploader post delete [flags] ID     delete the post and all of its images
+  image-uploader post favorite [flags] ID   add the post to favorites, or remove it
+
+ID is the imgchest post ID or URL. Every action requires an imgchest API token.
+`
+
+func runPostCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, postUsage)
+		return exitUsage
+	}
+	action := args[0]
+	switch action {
+	case "show", "delete", "favorite":
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, postUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown post action %q\n\n%s", action, postUsage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("post "+action, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	token := fs.String("token", "", "imgchest API token (default: $IMGCHEST_TOKEN or imgchest.txt)")
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 || imgchestPostID(positional[0]) == "" {
+		fmt.Fprintln(stderr, "expected exactly one post ID")
+		return exitUsage
+	}
+	postID := imgchestPostID(positional[0])
+
+	SetImgchestToken(firstNonEmpty(*token, os.Getenv("IMGCHEST_TOKEN")))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch action {
+	case "show":
+		var post *ImgchestPost
+		if post, err = getImgchestPost(ctx, postID); err == nil {
+			printImgchestPost(stdout, post)
+		}
+	case "delete":
+		if err = deleteImgchestPost(ctx, postID); err == nil {
+			fmt.Fprintf(stderr, "Deleted post %s\n", postID)
+		}
+	case "favorite":
+		var message string
+		if message, err = favoriteImgchestPost(ctx, postID); err == nil {
+			fmt.Fprintln(stderr, firstNonEmpty(message, "Done"))
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "Cancelled")
+			return exitCancelled
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runPostsCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("posts", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	page := fs.Int("page", 1, "page of posts to list")
+	all := fs.Bool("all", false, "list every page")
+	token := fs.String("token", "", "imgchest API token (default: $IMGCHEST_TOKEN or imgchest.txt)")
+
+	positional, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) > 0 {
+		fmt.Fprintf(stderr, "unexpected argument %q\n", positional[0])
+		return exitUsage
+	}
+	if *page < 1 {
+		fmt.Fprintln(stderr, "-page must be at least 1")
+		return exitUsage
+	}
+
+	SetImgchestToken(firstNonEmpty(*token, os.Getenv("IMGCHEST_TOKEN")))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	user, err := getImgchestUser(ctx)
+	if err == nil {
+		fmt.Fprintf(stderr, "Posts of %s\n", user.DisplayName())
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tPRIVACY\tIMAGES\tLINK\tTITLE")
+		p, more := *page, true
+		for ; more && err == nil; p++ {
+			var posts []ImgchestPost
+			posts, more, err = listImgchestUserPosts(ctx, user.DisplayName(), p)
+			for _, post := range posts {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", post.ID, post.Privacy, post.ImageCount, post.URL(), post.Title)
+			}
+			if !*all {
+				p++
+				break
+			}
+		}
+		tw.Flush()
+		if err == nil && more {
+			fmt.Fprintf(stderr, "More posts follow; run with -page %d or -all\n", p)
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "Cancelled")
+			return exitCancelled
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func printImgchestPost(w io.Writer, post *ImgchestPost) {
+	fmt.Fprintf(w, "%s\n", post.URL())
+	fmt.Fprintf(w, "Title:   %s\n", post.Title)
+	fmt.Fprintf(w, "Privacy: %s\n", post.Privacy)
+	fmt.Fprintf(w, "NSFW:    %v\n", post.NSFW != 0)
+	fmt.Fprintf(w, "Views:   %d\n", post.Views)
+	fmt.Fprintf(w, "Images:  %d\n\n", post.ImageCount)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tID\tLINK\tDESCRIPTION")
+	for _, img := range post.Images {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", img.Position, img.ID, img.Link, strings.ReplaceAll(img.Description, "\n", " "))
+	}
+	tw.Flush()
+}
+
+const fileUsage = `Usage:
+  image-uploader file show [flags] ID                    show the image's details
+  image-uploader file update -desc TEXT [flags] ID       set the image's description
+  image-uploader file delete [flags] ID                  delete the image from its post
+
+ID is the imgchest file ID or image link. Every action requires an imgchest
+API token.
+`
+
+func runFileCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, fileUsage)
+		return exitUsage
+	}
+	action := args[0]
+	switch action {
+	case "show", "update", "delete":
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, fileUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown file action %q\n\n%s", action, fileUsage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("file "+action, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	token := fs.String("token", "", "imgchest API token (default: $IMGCHEST_TOKEN or imgchest.txt)")
+	var desc *string
+	if action == "update" {
+		desc = fs.String("desc", "", "new image description")
+	}
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if desc != nil && strings.TrimSpace(*desc) == "" {
+		fmt.Fprintln(stderr, "-desc is required")
+		return exitUsage
+	}
+	if len(positional) != 1 || imgchestFileID(positional[0]) == "" {
+		fmt.Fprintln(stderr, "expected exactly one file ID")
+		return exitUsage
+	}
+	fileID := imgchestFileID(positional[0])
+
+	SetImgchestToken(firstNonEmpty(*token, os.Getenv("IMGCHEST_TOKEN")))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch action {
+	case "show":
+		var img *ImgchestImage
+		if img, err = getImgchestFile(ctx, fileID); err == nil {
+			fmt.Fprintf(stdout, "%s\n", img.Link)
+			fmt.Fprintf(stdout, "ID:          %s\n", img.ID)
+			fmt.Fprintf(stdout, "Position:    %d\n", img.Position)
+			fmt.Fprintf(stdout, "Name:        %s\n", img.OriginalName)
+			fmt.Fprintf(stdout, "Created:     %s\n", img.Created)
+			fmt.Fprintf(stdout, "Description: %s\n", img.Description)
+		}
+	case "update":
+		if err = updateImgchestFile(ctx, fileID, strings.TrimSpace(*desc)); err == nil {
+			fmt.Fprintf(stderr, "Updated file %s\n", fileID)
+		}
+	case "delete":
+		if err = deleteImgchestFile(ctx, fileID); err == nil {
+			fmt.Fprintf(stderr, "Deleted file %s\n", fileID)
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "Cancelled")
+			return exitCancelled
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+const kekUsage = `Usage:
+  image-uploader kek list [flags]            list your posts, newest first
+  image-uploader kek delete [flags] ID...    delete posts
+  image-uploader kek public [flags] ID...    make posts public
+  image-uploader kek private [flags] ID...   make posts private (depends on your plan)
+  image-uploader kek clear -yes [flags]      delete every post in your storage
+
+ID is the kek post ID. Every action requires a kek API key.
+`
+
+func runKekCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, kekUsage)
+		return exitUsage
+	}
+	action := args[0]
+	switch action {
+	case "list", "delete", "public", "private", "clear":
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, kekUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown kek action %q\n\n%s", action, kekUsage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("kek "+action, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	apiKey := fs.String("api-key", "", "kek API key (default: $KEK_API_KEY or kek.txt)")
+	var from *string
+	var all, yes *bool
+	switch action {
+	case "list":
+		from = fs.String("from", "", "list posts older than this post ID")
+		all = fs.Bool("all", false, "list every post")
+	case "clear":
+		yes = fs.Bool("yes", false, "confirm that every post should be deleted")
+	}
+
+	ids, err := parseInterspersed(fs, args[1:])
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	switch action {
+	case "list", "clear":
+		if len(ids) > 0 {
+			fmt.Fprintf(stderr, "unexpected argument %q\n", ids[0])
+			return exitUsage
+		}
+	default:
+		if len(ids) == 0 {
+			fmt.Fprintln(stderr, "expected at least one post ID")
+			return exitUsage
+		}
+	}
+	if action == "clear" && !*yes {
+		fmt.Fprintln(stderr, "clear deletes every post in your kek storage and cannot be undone; run again with -yes to confirm")
+		return exitUsage
+	}
+
+	SetKekAPIKey(firstNonEmpty(*apiKey, os.Getenv("KEK_API_KEY")))
+	key, err := getKekAPIKey()
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitFailure
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var failed bool
+	switch action {
+	case "list":
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tPUBLIC\tMATURE\tCREATED\tLINK")
+		next := *from
+		for {
+			var posts []KekPost
+			if posts, err = listKekPosts(ctx, key, next); err != nil {
+				break
+			}
+			for _, post := range posts {
+				fmt.Fprintf(tw, "%s\t%v\t%v\t%s\t%s\n", post.GetID(), post.Public, post.Mature, post.CreatedAt, post.GetURL())
+			}
+			if len(posts) < kekPostsPageSize {
+				break
+			}
+			next = posts[len(posts)-1].GetID()
+			if !*all {
+				tw.Flush()
+				fmt.Fprintf(stderr, "More posts follow; run with -from %s or -all\n", next)
+				break
+			}
+		}
+		tw.Flush()
+	case "clear":
+		if err = clearKekStorage(ctx, key); err == nil {
+			fmt.Fprintln(stderr, "Cleared kek storage")
+		}
+	default:
+		for _, id := range ids {
+			var opErr error
+			switch action {
+			case "delete":
+				opErr = deleteKekPost(ctx, id, key)
+			case "public", "private":
+				opErr = setKekPostPublic(ctx, id, key, action == "public")
+			}
+			if opErr != nil {
+				if ctx.Err() != nil {
+					err = opErr
+					break
+				}
+				failed = true
+				fmt.Fprintf(stderr, "error: %s: %v\n", id, opErr)
+				continue
+			}
+			if action == "delete" {
+				fmt.Fprintf(stdout, "deleted %s\n", id)
+			} else {
+				fmt.Fprintf(stdout, "made %s %s\n", action, id)
+			}
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "Cancelled")
+			return exitCancelled
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runDeleteCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	provider := fs.String("provider", "catbox", "provider the files were uploaded to (sxcu also accepts deletion URLs)")
+	userhash := fs.String("userhash", "", "catbox userhash (default: $CATBOX_USERHASH or catbox.txt)")
+	token := fs.String("token", "", "imgchest API token (default: $IMGCHEST_TOKEN or imgchest.txt)")
+	apiKey := fs.String("api-key", "", "kek API key (default: $KEK_API_KEY or kek.txt)")
+
+	targets, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	p, ok := LookupProvider(*provider)
+	if !ok {
+		fmt.Fprintf(stderr, "unknown provider %q (expected %s)\n", *provider, strings.Join(ProviderNames(), ", "))
+		return exitUsage
+	}
+	if _, ok := p.(UploadDeleter); !ok {
+		fmt.Fprintf(stderr, "deleting uploads is not supported by %s\n", p.Name())
+		return exitUsage
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(stderr, "no files to delete")
+		fs.Usage()
+		return exitUsage
+	}
+
+	urls := make([]string, 0, len(targets))
+	for _, t := range targets {
+		t = strings.TrimSpace(t)
+		if p.Name() == "catbox" && !strings.Contains(t, "://") {
+			t = "https://files.catbox.moe/" + t
+		}
+		urls = append(urls, t)
+	}
+
+	SetCatboxUserhash(firstNonEmpty(*userhash, os.Getenv("CATBOX_USERHASH")))
+	SetImgchestToken(firstNonEmpty(*token, os.Getenv("IMGCHEST_TOKEN")))
+	SetKekAPIKey(firstNonEmpty(*apiKey, os.Getenv("KEK_API_KEY")))
+
+	store, err := OpenHistoryStore()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open history: %v\n", err)
+		return exitFailure
+	}
+	entries, err := store.FindByURL(p.Name(), urls)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitFailure
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	deleted, errors := deleteHistoryEntries(ctx, store, entries)
+	for _, e := range deleted {
+		fmt.Fprintf(stdout, "deleted %s\n", e.URL)
+	}
+	for _, e := range errors {
+		fmt.Fprintf(stderr, "error: %s\n", e)
+	}
+	if ctx.Err() != nil {
+		return exitCancelled
+	}
+	if len(errors) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runShortenCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("shorten", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	links, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(links) == 0 {
+		fmt.Fprintln(stderr, "no links to shorten")
+		fs.Usage()
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	items, errors := shortenLinks(ctx, links)
+	if err := recordShortLinks(items); err != nil {
+		fmt.Fprintf(stderr, "warning: failed to record history: %v\n", err)
+	}
+	for _, item := range items {
+		fmt.Fprintln(stdout, item.URL)
+	}
+	for _, d := range (UploadSummary{Items: items}).Details() {
+		fmt.Fprintln(stderr, d)
+	}
+	for _, e := range errors {
+		fmt.Fprintf(stderr, "error: %s\n", e)
+	}
+	if ctx.Err() != nil {
+		return exitCancelled
+	}
+	if len(errors) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
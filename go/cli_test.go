@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunCLIUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no command", nil, "Usage:"},
+		{"unknown command", []string{"frobnicate"}, `unknown command "frobnicate"`},
+		{"unknown provider", []string{"upload", "--provider", "nope", "a.png"}, `unknown provider "nope"`},
+		{"nothing to upload", []string{"upload", "--provider", "sxcu"}, "no files or URLs to upload"},
+		{"url on sxcu", []string{"upload", "--provider", "sxcu", "--urls", "https://example.com/a.png"}, "URL uploads are not supported by sxcu"},
+		{"bad privacy", []string{"upload", "--provider", "imgchest", "--privacy", "open", "a.png"}, `invalid privacy "open"`},
+		{"album and album-short", []string{"upload", "--album", "--album-short", "pd412w", "a.png"}, "cannot be combined"},
+		{"bad history date", []string{"history", "--date", "13/10/2026"}, `invalid date "13/10/2026"`},
+		{"unknown album action", []string{"album", "rename", "pd412w"}, `unknown album action "rename"`},
+		{"album without short", []string{"album", "delete"}, "missing album short code"},
+		{"album edit without files", []string{"album", "edit", "pd412w", "--title", "X", "--desc", ""}, "list every file to keep"},
+		{"album edit without desc", []string{"album", "edit", "pd412w", "--title", "X", "a.png"}, "pass both -title and -desc"},
+		{"bad expiry", []string{"upload", "--expiry", "2h", "a.png"}, `invalid expiry "2h"`},
+		{"expiry on sxcu", []string{"upload", "--provider", "sxcu", "--expiry", "1h", "a.png"}, "only supported by catbox"},
+		{"expiry with album", []string{"upload", "--expiry", "12h", "--album", "a.png"}, "cannot be added to albums"},
+		{"subdomain on catbox", []string{"upload", "--subdomain", "team", "a.png"}, "only supported by sxcu"},
+		{"og on catbox", []string{"upload", "--og-title", "Hi", "a.png"}, "only supported by sxcu"},
+		{"bad og color", []string{"upload", "--provider", "sxcu", "--og-color", "blue", "a.png"}, `invalid embed color "blue"`},
+		{"self-destruct on kek", []string{"upload", "--provider", "kek", "--self-destruct", "a.png"}, "only supported by sxcu"},
+		{"paste on catbox", []string{"upload", "--paste", "a.txt"}, "-paste is only supported by sxcu"},
+		{"paste with self-destruct", []string{"upload", "--provider", "sxcu", "--paste", "--self-destruct", "a.txt"}, "-paste cannot be combined"},
+		{"paste with embed", []string{"upload", "--provider", "sxcu", "--paste", "--og-title", "Hi", "a.txt"}, "do not apply to pastes"},
+		{"collection and collection-id", []string{"upload", "--provider", "sxcu", "--collection", "--collection-id", "abc", "a.png"}, "cannot be combined"},
+		{"descriptions on sxcu", []string{"upload", "--provider", "sxcu", "--descriptions", "d.txt", "a.png"}, "only supported by imgchest"},
+		{"shorten nothing", []string{"shorten"}, "no links to shorten"},
+		{"unknown post action", []string{"post", "rename", "abc"}, `unknown post action "rename"`},
+		{"post without id", []string{"post", "show"}, "expected exactly one post ID"},
+		{"posts bad page", []string{"posts", "--page", "0"}, "-page must be at least 1"},
+		{"delete nothing", []string{"delete"}, "no files to delete"},
+		{"visibility on catbox", []string{"upload", "--visibility", "private", "a.png"}, "only supported by kek"},
+		{"bad visibility", []string{"upload", "--provider", "kek", "--visibility", "hidden", "a.png"}, `invalid visibility "hidden"`},
+		{"kek clear without confirmation", []string{"kek", "clear"}, "run again with -yes"},
+		{"kek delete without id", []string{"kek", "delete"}, "expected at least one post ID"},
+		{"unknown file action", []string{"file", "move", "abc"}, `unknown file action "move"`},
+		{"file update without desc", []string{"file", "update", "abc"}, "-desc is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCLI(tt.args, &stdout, &stderr); code != exitUsage {
+				t.Fatalf("exit code = %d, want %d", code, exitUsage)
+			}
+			if !strings.Contains(stderr.String(), tt.want) {
+				t.Fatalf("stderr = %q, want it to contain %q", stderr.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCLIInvocation(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"upload", "a.png"}, true},
+		{[]string{"--help"}, true},
+		{[]string{`C:\Pictures\a.png`}, false},
+		{[]string{"a.png", "upload"}, false},
+	}
+	for _, tt := range tests {
+		if got := isCLIInvocation(tt.args); got != tt.want {
+			t.Errorf("isCLIInvocation(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	title := fs.String("title", "", "")
+	collection := fs.Bool("collection", false, "")
+
+	files, err := parseInterspersed(fs, []string{"a.png", "--title", "X", "b.png", "--collection", "--", "--c.png", "--d.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.png", "b.png", "--c.png", "--d.png"}; !reflect.DeepEqual(files, want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+	if *title != "X" || !*collection {
+		t.Fatalf("flags = (%q, %v), want (\"X\", true)", *title, *collection)
+	}
+}
+
+func TestReadImageDescriptions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "descriptions.txt")
+	content := "# captions\r\na.png\tFirst shot\r\n" + filepath.Join("shots", "b.png") + "\t Second shot \n\nunused.png\tNothing\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{filepath.Join("shots", "a.png"), filepath.Join("shots", "b.png"), "c.png"}
+	got, err := readImageDescriptions(path, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{files[0]: "First shot", files[1]: "Second shot"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("readImageDescriptions() = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("a.png First shot\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readImageDescriptions(path, files); err == nil || !strings.Contains(err.Error(), ":1: expected FILE<TAB>DESCRIPTION") {
+		t.Fatalf("readImageDescriptions() error = %v", err)
+	}
+}
@@ -1,55 +1,87 @@
+//go:build windows
+
 package main
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
 )
 
-var timeNow = time.Now
-var timeSleep = time.Sleep
-
 type App struct {
-	mainWindow        *walk.MainWindow
-	fileListBox       *walk.ListBox
-	fileListModel     *FileListModel
-	urlEdit           *walk.LineEdit
-	titleEdit         *walk.LineEdit
-	titleComposite    *walk.Composite
-	descEdit          *walk.LineEdit
-	descComposite     *walk.Composite
-	providerCombo     *walk.ComboBox
-	albumCheck        *walk.CheckBox
-	collectionCheck   *walk.CheckBox
-	sxcuPrivateCheck  *walk.CheckBox
-	anonymousCheck    *walk.CheckBox
-	privacyCombo      *walk.ComboBox
-	nsfwCheck         *walk.CheckBox
-	kekApiKeyEdit     *walk.LineEdit
-	kekMatureCheck    *walk.CheckBox
-	postIDEdit        *walk.LineEdit
-	imgchestTokenEdit *walk.LineEdit
-	outputEdit        *walk.TextEdit
-	uploadButton      *walk.PushButton
-	copyButton        *walk.PushButton
-	selectedFiles     []string
-	uploadCompleted   bool
-	copiedLinks       []string
+	mainWindow          *walk.MainWindow
+	fileListBox         *walk.ListBox
+	fileListModel       *FileListModel
+	urlEdit             *walk.LineEdit
+	titleEdit           *walk.LineEdit
+	titleComposite      *walk.Composite
+	descEdit            *walk.LineEdit
+	descComposite       *walk.Composite
+	providerCombo       *walk.ComboBox
+	albumCheck          *walk.CheckBox
+	collectionCheck     *walk.CheckBox
+	sxcuPrivateCheck    *walk.CheckBox
+	sxcuSubdomainEdit   *walk.LineEdit
+	sxcuUploadTokenEdit *walk.LineEdit
+	sxcuCheckButton     *walk.PushButton
+	selfDestructCheck   *walk.CheckBox
+	sxcuPasteCheck      *walk.CheckBox
+	sxcuCollectionEdit  *walk.LineEdit
+	sxcuCollTokenEdit   *walk.LineEdit
+	sxcuEmbedLabel      *walk.Label
+	sxcuEmbedButton     *walk.PushButton
+	anonymousCheck      *walk.CheckBox
+	privacyCombo        *walk.ComboBox
+	nsfwCheck           *walk.CheckBox
+	kekApiKeyEdit       *walk.LineEdit
+	catboxUserhashEdit  *walk.LineEdit
+	albumShortEdit      *walk.LineEdit
+	manageAlbumButton   *walk.PushButton
+	expiryCombo         *walk.ComboBox
+	kekMatureCheck      *walk.CheckBox
+	kekVisibilityCombo  *walk.ComboBox
+	postIDEdit          *walk.LineEdit
+	viewPostButton      *walk.PushButton
+	browsePostsButton   *walk.PushButton
+	kekPostsButton      *walk.PushButton
+	imgchestTokenEdit   *walk.LineEdit
+	outputEdit          *walk.TextEdit
+	uploadButton        *walk.PushButton
+	cancelButton        *walk.PushButton
+	copyButton          *walk.PushButton
+	shortenButton       *walk.PushButton
+	historyButton       *walk.PushButton
+	selectedFiles       []string
+	pendingFiles        []string
+	uploadCompleted     bool
+	copiedLinks         []string
+	cancelUpload        context.CancelFunc
+	sxcuOG              SxcuOGProperties
 
 	urlComposite          *walk.Composite
 	catboxOptsComposite   *walk.Composite
 	sxcuOptsComposite     *walk.Composite
 	imgchestOptsComposite *walk.Composite
 	kekOptsComposite      *walk.Composite
+	providerPanels        map[string]providerPanel
+}
+
+// providerPanel is the options composite of one provider. reset restores its
+// widgets whenever the selected provider changes; active tells whether the
+// panel belongs to the newly selected provider.
+type providerPanel struct {
+	composite *walk.Composite
+	reset     func(active bool)
 }
 
 type FileItem struct {
 	Path string
 	Base string
+	Desc string
 }
 
 type FileListModel struct {
@@ -63,11 +95,22 @@ func (m *FileListModel) ItemCount() int {
 
 func (m *FileListModel) Value(index int) interface{} {
 	if index >= 0 && index < len(m.items) {
+		if m.items[index].Desc != "" {
+			return m.items[index].Base + " — " + m.items[index].Desc
+		}
 		return m.items[index].Base
 	}
 	return ""
 }
 
+// runGUI opens the main window with files, such as those passed by "Open
+// with", already in the list.
+func runGUI(files []string) error {
+	a := NewApp()
+	a.pendingFiles = files
+	return a.Run()
+}
+
 func NewApp() *App {
 	return &App{
 		fileListModel: &FileListModel{items: make([]FileItem, 0, 32)},
@@ -75,7 +118,13 @@ func NewApp() *App {
 }
 
 func (a *App) Run() error {
-	providers := []string{"catbox", "sxcu", "imgchest", "kek"}
+	providers := ProviderNames()
+	defaultIndex := 0
+	for i, name := range providers {
+		if name == defaultProviderName {
+			defaultIndex = i
+		}
+	}
 
 	icon, _ := walk.NewIconFromFile("favicon.ico")
 
@@ -98,13 +147,20 @@ func (a *App) Run() error {
 							ComboBox{
 								AssignTo:              &a.providerCombo,
 								Model:                 providers,
-								CurrentIndex:          2,
+								CurrentIndex:          defaultIndex,
 								OnCurrentIndexChanged: a.onProviderChanged,
 								MinSize:               Size{Width: 90},
 							},
 						},
 					},
 					HSpacer{},
+					PushButton{
+						AssignTo:  &a.historyButton,
+						Text:      "History",
+						OnClicked: a.onShowHistory,
+						MinSize:   Size{Width: 60},
+						MaxSize:   Size{Width: 60},
+					},
 					PushButton{
 						Text:      "＋",
 						OnClicked: a.onSelectFiles,
@@ -127,11 +183,13 @@ func (a *App) Run() error {
 			},
 
 			ListBox{
-				AssignTo:       &a.fileListBox,
-				Model:          a.fileListModel,
-				MinSize:        Size{Height: 90},
-				MultiSelection: true,
-				OnKeyDown:      a.onFileListKeyDown,
+				AssignTo:        &a.fileListBox,
+				Model:           a.fileListModel,
+				MinSize:         Size{Height: 90},
+				MultiSelection:  true,
+				OnKeyDown:       a.onFileListKeyDown,
+				OnItemActivated: a.onEditFileDescription,
+				ToolTipText:     "Double-click a file to set its Image Chest description",
 			},
 
 			Composite{
@@ -169,31 +227,138 @@ func (a *App) Run() error {
 
 			Composite{
 				AssignTo: &a.catboxOptsComposite,
-				Layout:   HBox{MarginsZero: true},
+				Layout:   VBox{MarginsZero: true, Spacing: 8},
 				Children: []Widget{
-					CheckBox{
-						AssignTo: &a.albumCheck,
-						Text:     "Create Album",
-						Checked:  true,
+					Composite{
+						Layout: HBox{MarginsZero: true, Spacing: 6},
+						Children: []Widget{
+							Label{Text: "Userhash:", MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+							LineEdit{
+								AssignTo:     &a.catboxUserhashEdit,
+								PasswordMode: true,
+							},
+						},
+					},
+					Composite{
+						Layout: HBox{MarginsZero: true, Spacing: 6},
+						Children: []Widget{
+							Label{Text: "Album:", MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+							LineEdit{
+								AssignTo:      &a.albumShortEdit,
+								OnTextChanged: a.onAlbumShortChanged,
+								ToolTipText:   "Short code of an existing album to add the uploads to",
+							},
+							PushButton{
+								AssignTo:  &a.manageAlbumButton,
+								Text:      "Manage…",
+								OnClicked: a.onManageAlbum,
+							},
+						},
+					},
+					Composite{
+						Layout: HBox{MarginsZero: true, Spacing: 6},
+						Children: []Widget{
+							CheckBox{
+								AssignTo: &a.albumCheck,
+								Text:     "Create Album",
+								Checked:  true,
+							},
+							HSpacer{},
+							Label{Text: "Expiry:"},
+							ComboBox{
+								AssignTo:              &a.expiryCombo,
+								Model:                 []string{"Permanent", "1 hour", "12 hours", "24 hours", "72 hours"},
+								CurrentIndex:          0,
+								OnCurrentIndexChanged: a.onExpiryChanged,
+								ToolTipText:           "Temporary uploads go to Litterbox",
+								MinSize:               Size{Width: 90},
+								MaxSize:               Size{Width: 90},
+							},
+						},
 					},
 				},
 			},
 
 			Composite{
 				AssignTo: &a.sxcuOptsComposite,
-				Layout:   HBox{MarginsZero: true, Spacing: 12},
+				Layout:   VBox{MarginsZero: true, Spacing: 8},
 				Visible:  false,
 				Children: []Widget{
-					CheckBox{
-						AssignTo:         &a.collectionCheck,
-						Text:             "Create Collection",
-						Checked:          true,
-						OnCheckedChanged: a.onSxcuCollectionChanged,
+					Composite{
+						Layout: HBox{MarginsZero: true, Spacing: 6},
+						Children: []Widget{
+							Label{Text: "Subdomain:", MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+							LineEdit{
+								AssignTo:    &a.sxcuSubdomainEdit,
+								ToolTipText: "Leave empty to upload to sxcu.net",
+							},
+							Label{Text: "Token:"},
+							LineEdit{
+								AssignTo:     &a.sxcuUploadTokenEdit,
+								PasswordMode: true,
+								ToolTipText:  "Upload token of a private subdomain",
+							},
+							PushButton{
+								AssignTo:  &a.sxcuCheckButton,
+								Text:      "Check",
+								OnClicked: a.onCheckSxcuSubdomain,
+							},
+						},
 					},
-					CheckBox{
-						AssignTo: &a.sxcuPrivateCheck,
-						Text:     "Private",
-						Checked:  true,
+					Composite{
+						Layout: HBox{MarginsZero: true, Spacing: 6},
+						Children: []Widget{
+							Label{Text: "Collection:", MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+							LineEdit{
+								AssignTo:      &a.sxcuCollectionEdit,
+								OnTextChanged: a.onSxcuCollectionIDChanged,
+								ToolTipText:   "ID or link of an existing collection to add the uploads to",
+							},
+							Label{Text: "Token:"},
+							LineEdit{
+								AssignTo:     &a.sxcuCollTokenEdit,
+								PasswordMode: true,
+								ToolTipText:  "Collection token; filled in automatically for collections created here",
+							},
+						},
+					},
+					Composite{
+						Layout: HBox{MarginsZero: true, Spacing: 12},
+						Children: []Widget{
+							CheckBox{
+								AssignTo:         &a.collectionCheck,
+								Text:             "Create Collection",
+								Checked:          true,
+								OnCheckedChanged: a.onSxcuCollectionChanged,
+							},
+							CheckBox{
+								AssignTo: &a.sxcuPrivateCheck,
+								Text:     "Private",
+								Checked:  true,
+							},
+							CheckBox{
+								AssignTo:    &a.selfDestructCheck,
+								Text:        "Self-destruct",
+								ToolTipText: "Delete the uploads automatically after 24 hours",
+							},
+							CheckBox{
+								AssignTo:         &a.sxcuPasteCheck,
+								Text:             "Text paste",
+								ToolTipText:      "Upload the files as cancer-co.de text pastes instead of sxcu files",
+								OnCheckedChanged: a.onSxcuPasteChanged,
+							},
+							HSpacer{},
+							Label{Text: "Embed:"},
+							Label{
+								AssignTo: &a.sxcuEmbedLabel,
+								Text:     embedSummary(SxcuOGProperties{}),
+							},
+							PushButton{
+								AssignTo:  &a.sxcuEmbedButton,
+								Text:      "Edit…",
+								OnClicked: a.onEditEmbed,
+							},
+						},
 					},
 				},
 			},
@@ -246,6 +411,19 @@ func (a *App) Run() error {
 								AssignTo:      &a.postIDEdit,
 								OnTextChanged: a.onPostIDChanged,
 							},
+							PushButton{
+								AssignTo:    &a.viewPostButton,
+								Text:        "View…",
+								Enabled:     false,
+								ToolTipText: "View, favorite or delete this post",
+								OnClicked:   a.onViewPost,
+							},
+							PushButton{
+								AssignTo:    &a.browsePostsButton,
+								Text:        "Browse…",
+								ToolTipText: "Pick one of your posts to add the uploads to",
+								OnClicked:   a.onBrowsePosts,
+							},
 						},
 					},
 				},
@@ -274,6 +452,23 @@ func (a *App) Run() error {
 								Text:     "Mature / NSFW",
 								Checked:  true,
 							},
+							HSpacer{},
+							Label{Text: "Visibility:"},
+							ComboBox{
+								AssignTo:     &a.kekVisibilityCombo,
+								Model:        []string{"Default", "Public", "Private"},
+								CurrentIndex: 0,
+								ToolTipText:  "Private posts need a kek plan that allows them",
+								MinSize:      Size{Width: 80},
+								MaxSize:      Size{Width: 80},
+							},
+							HSpacer{},
+							PushButton{
+								AssignTo:    &a.kekPostsButton,
+								Text:        "Posts…",
+								ToolTipText: "List, delete or change your kek posts",
+								OnClicked:   a.onManageKekPosts,
+							},
 						},
 					},
 				},
@@ -287,18 +482,45 @@ func (a *App) Run() error {
 			},
 
 			PushButton{
-				AssignTo:  &a.copyButton,
-				Text:      "⧉ Copy Links",
-				OnClicked: a.onCopyLinks,
+				AssignTo:  &a.cancelButton,
+				Text:      "✕ Cancel",
+				OnClicked: a.onCancel,
 				MinSize:   Size{Height: 32},
 				Visible:   false,
 			},
 
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					PushButton{
+						AssignTo:  &a.copyButton,
+						Text:      "⧉ Copy Links",
+						OnClicked: a.onCopyLinks,
+						MinSize:   Size{Height: 32},
+						Visible:   false,
+					},
+					PushButton{
+						AssignTo:    &a.shortenButton,
+						Text:        "🔗 Shorten",
+						OnClicked:   a.onShortenLinks,
+						ToolTipText: "Replace the links with sxcu short links",
+						MinSize:     Size{Height: 32},
+						MaxSize:     Size{Width: 110},
+						Visible:     false,
+					},
+				},
+			},
+
 			TextEdit{
 				AssignTo: &a.outputEdit,
 				ReadOnly: true,
 				VScroll:  true,
 				MinSize:  Size{Height: 100},
+				ContextMenuItems: []MenuItem{
+					Action{Text: "Image Info", OnTriggered: a.onResultFileInfo},
+					Action{Text: "Edit Description…", OnTriggered: a.onResultEditDescription},
+					Action{Text: "Delete Image", OnTriggered: a.onResultDeleteImage},
+				},
 			},
 		},
 	}.Create()
@@ -307,12 +529,26 @@ func (a *App) Run() error {
 		return err
 	}
 
+	a.providerPanels = map[string]providerPanel{
+		catboxProvider{}.Name():   {a.catboxOptsComposite, a.resetCatboxOptions},
+		sxcuProvider{}.Name():     {a.sxcuOptsComposite, a.resetSxcuOptions},
+		imgchestProvider{}.Name(): {a.imgchestOptsComposite, a.resetImgchestOptions},
+		kekProvider{}.Name():      {a.kekOptsComposite, a.resetKekOptions},
+	}
+
 	if IsSystemDarkMode() {
 		SetDarkModeTitleBar(uintptr(a.mainWindow.Handle()), true)
 		ApplyDarkTheme(a)
 	}
 
+	a.mainWindow.Closing().Attach(func(canceled *bool, reason walk.CloseReason) {
+		if a.cancelUpload != nil {
+			a.cancelUpload()
+		}
+	})
+
 	a.onProviderChanged()
+	a.addFiles(a.pendingFiles)
 
 	a.mainWindow.Run()
 	return nil
@@ -320,66 +556,89 @@ func (a *App) Run() error {
 
 func (a *App) onProviderChanged() {
 	provider := a.providerCombo.Text()
+	var caps ProviderCapabilities
+	if p, ok := LookupProvider(provider); ok {
+		caps = p.Capabilities()
+	}
 
-	isCatbox := provider == "catbox"
-	isSxcu := provider == "sxcu"
-	isImgchest := provider == "imgchest"
-	isKek := provider == "kek"
+	for _, name := range ProviderNames() {
+		panel, ok := a.providerPanels[name]
+		if !ok {
+			continue
+		}
+		active := name == provider
+		panel.composite.SetVisible(active)
+		panel.reset(active)
+	}
 
-	a.urlComposite.SetVisible(isCatbox || isKek)
-	if !isCatbox && !isKek {
+	a.urlComposite.SetVisible(caps.URLUpload)
+	if !caps.URLUpload {
 		a.urlEdit.SetText("")
 	}
 
-	a.catboxOptsComposite.SetVisible(isCatbox)
-	a.albumCheck.SetEnabled(isCatbox)
-	if isCatbox {
-		a.albumCheck.SetChecked(true)
-	} else {
-		a.albumCheck.SetChecked(false)
+	a.titleComposite.SetVisible(caps.Title)
+	a.descComposite.SetVisible(caps.Description)
+	if !caps.Title {
+		a.titleEdit.SetText("")
 	}
-
-	a.sxcuOptsComposite.SetVisible(isSxcu)
-	a.collectionCheck.SetEnabled(isSxcu)
-	if isSxcu {
-		a.collectionCheck.SetChecked(true)
-	} else {
-		a.collectionCheck.SetChecked(false)
+	if !caps.Description {
+		a.descEdit.SetText("")
 	}
 
-	a.imgchestOptsComposite.SetVisible(isImgchest)
-	a.anonymousCheck.SetEnabled(isImgchest)
-	if !isImgchest {
-		a.anonymousCheck.SetChecked(false)
-		a.postIDEdit.SetText("")
+	if a.mainWindow != nil {
+		a.mainWindow.Invalidate()
 	}
-	a.postIDEdit.SetEnabled(isImgchest && !a.anonymousCheck.Checked())
+}
 
-	a.kekOptsComposite.SetVisible(isKek)
-	a.kekApiKeyEdit.SetEnabled(isKek)
-	a.kekMatureCheck.SetEnabled(isKek)
-	if isKek {
-		a.kekMatureCheck.SetChecked(true)
+// selected reports whether p is the provider chosen in the combo box.
+func (a *App) selected(p Provider) bool {
+	return a.providerCombo.Text() == p.Name()
+}
+
+func (a *App) resetCatboxOptions(active bool) {
+	a.albumCheck.SetEnabled(active && a.albumShortEdit.Text() == "")
+	a.albumCheck.SetChecked(active)
+	if !active {
+		a.albumShortEdit.SetText("")
 	}
+	a.expiryCombo.SetCurrentIndex(0)
+	a.albumShortEdit.SetEnabled(true)
+}
 
-	a.titleComposite.SetVisible(!isKek)
-	a.descComposite.SetVisible(!isImgchest && !isKek)
-	if isKek {
-		a.titleEdit.SetText("")
+func (a *App) resetSxcuOptions(active bool) {
+	a.sxcuPasteCheck.SetChecked(false)
+	a.collectionCheck.SetEnabled(active)
+	a.collectionCheck.SetChecked(active)
+	if !active {
+		a.sxcuCollectionEdit.SetText("")
+		a.sxcuCollTokenEdit.SetText("")
 	}
-	if isImgchest || isKek {
-		a.descEdit.SetText("")
+}
+
+func (a *App) resetImgchestOptions(active bool) {
+	a.anonymousCheck.SetEnabled(active)
+	if !active {
+		a.anonymousCheck.SetChecked(false)
+		a.postIDEdit.SetText("")
 	}
+	a.postIDEdit.SetEnabled(active && !a.anonymousCheck.Checked())
+	a.browsePostsButton.SetEnabled(a.postIDEdit.Enabled())
+}
 
-	if a.mainWindow != nil {
-		a.mainWindow.Invalidate()
+func (a *App) resetKekOptions(active bool) {
+	a.kekApiKeyEdit.SetEnabled(active)
+	a.kekMatureCheck.SetEnabled(active)
+	a.kekVisibilityCombo.SetEnabled(active)
+	if active {
+		a.kekMatureCheck.SetChecked(true)
 	}
 }
 
 func (a *App) onAnonymousChanged() {
-	if a.providerCombo.Text() == "imgchest" {
+	if a.selected(imgchestProvider{}) {
 		anonymous := a.anonymousCheck.Checked()
 		a.postIDEdit.SetEnabled(!anonymous)
+		a.browsePostsButton.SetEnabled(!anonymous)
 		a.privacyCombo.SetEnabled(!anonymous)
 		if anonymous {
 			a.postIDEdit.SetText("")
@@ -389,17 +648,138 @@ func (a *App) onAnonymousChanged() {
 	}
 }
 
+var litterboxExpiryOptions = []string{"", "1h", "12h", "24h", "72h"}
+
+func (a *App) litterboxExpiry() string {
+	if i := a.expiryCombo.CurrentIndex(); i > 0 && i < len(litterboxExpiryOptions) {
+		return litterboxExpiryOptions[i]
+	}
+	return ""
+}
+
+func (a *App) kekVisibility() string {
+	if a.kekVisibilityCombo.CurrentIndex() <= 0 {
+		return ""
+	}
+	return strings.ToLower(a.kekVisibilityCombo.Text())
+}
+
+func (a *App) onExpiryChanged() {
+	if !a.selected(catboxProvider{}) {
+		return
+	}
+	temporary := a.litterboxExpiry() != ""
+	a.urlComposite.SetVisible(!temporary)
+	a.albumShortEdit.SetEnabled(!temporary)
+	if temporary {
+		a.urlEdit.SetText("")
+		a.albumShortEdit.SetText("")
+		a.albumCheck.SetChecked(false)
+		a.albumCheck.SetEnabled(false)
+	} else {
+		a.albumCheck.SetEnabled(true)
+		a.albumCheck.SetChecked(true)
+	}
+}
+
+func (a *App) onAlbumShortChanged() {
+	if !a.selected(catboxProvider{}) {
+		return
+	}
+	hasAlbum := strings.TrimSpace(a.albumShortEdit.Text()) != ""
+	a.albumCheck.SetEnabled(!hasAlbum)
+	if hasAlbum {
+		a.albumCheck.SetChecked(false)
+	}
+}
+
 func (a *App) onSxcuCollectionChanged() {
 	createCollection := a.collectionCheck.Checked()
 	a.sxcuPrivateCheck.SetEnabled(createCollection)
 }
 
+// onSxcuPasteChanged turns off the options that only apply to sxcu file
+// uploads while pastes are selected.
+func (a *App) onSxcuPasteChanged() {
+	paste := a.sxcuPasteCheck.Checked()
+	if paste {
+		a.collectionCheck.SetChecked(false)
+		a.selfDestructCheck.SetChecked(false)
+		a.sxcuCollectionEdit.SetText("")
+		a.sxcuCollTokenEdit.SetText("")
+	}
+	a.collectionCheck.SetEnabled(!paste)
+	a.sxcuPrivateCheck.SetEnabled(!paste && a.collectionCheck.Checked())
+	a.selfDestructCheck.SetEnabled(!paste)
+	a.sxcuCollectionEdit.SetEnabled(!paste)
+	a.sxcuCollTokenEdit.SetEnabled(!paste)
+	a.sxcuEmbedButton.SetEnabled(!paste)
+}
+
+func (a *App) onSxcuCollectionIDChanged() {
+	if !a.selected(sxcuProvider{}) {
+		return
+	}
+	id := sxcuCollectionID(a.sxcuCollectionEdit.Text())
+	a.collectionCheck.SetEnabled(id == "")
+	a.sxcuPrivateCheck.SetEnabled(id == "" && a.collectionCheck.Checked())
+	if id == "" {
+		return
+	}
+	a.collectionCheck.SetChecked(false)
+	if a.sxcuCollTokenEdit.Text() == "" {
+		if token := rememberedGroupToken(sxcuProvider{}.Name(), id); token != "" {
+			a.sxcuCollTokenEdit.SetText(token)
+		}
+	}
+}
+
+func (a *App) onCheckSxcuSubdomain() {
+	subdomain := strings.TrimSpace(a.sxcuSubdomainEdit.Text())
+	uploadToken := strings.TrimSpace(a.sxcuUploadTokenEdit.Text())
+	if subdomain == "" {
+		showError("Enter a subdomain to check")
+		return
+	}
+
+	ctx, ok := a.startCancellable()
+	if !ok {
+		return
+	}
+	a.sxcuCheckButton.SetEnabled(false)
+	go func() {
+		info, err := validateSxcuSubdomain(ctx, subdomain, uploadToken)
+		a.mainWindow.Synchronize(func() {
+			a.sxcuCheckButton.SetEnabled(true)
+			cancelled := ctx.Err() != nil
+			a.finishUpload()
+			if cancelled {
+				return
+			}
+			if err != nil {
+				showError(err.Error())
+				return
+			}
+			visibility := "public"
+			if !info.Public {
+				visibility = "private"
+			}
+			_, host := normalizeSxcuSubdomain(subdomain)
+			message := fmt.Sprintf("%s is a %s subdomain with %d files and %d links.", host, visibility, info.Files, info.Links)
+			walk.MsgBox(a.mainWindow, "sxcu Subdomain", message, walk.MsgBoxOK|walk.MsgBoxIconInformation)
+		})
+	}()
+}
+
 func (a *App) onPostIDChanged() {
+	if a.viewPostButton != nil {
+		a.viewPostButton.SetEnabled(imgchestPostID(a.postIDEdit.Text()) != "")
+	}
 	a.updateNsfwCheckState()
 }
 
 func (a *App) updateNsfwCheckState() {
-	if a.providerCombo.Text() != "imgchest" {
+	if !a.selected(imgchestProvider{}) {
 		return
 	}
 	a.nsfwCheck.SetEnabled(true)
@@ -420,7 +800,7 @@ func (a *App) onSelectFiles() {
 
 	dlg := new(walk.FileDialog)
 	dlg.Title = "Select Files"
-	dlg.Filter = "Image files (*.jpg;*.jpeg;*.png;*.gif;*.bmp;*.ico;*.tif;*.tiff;*.webp)|*.jpg;*.jpeg;*.png;*.gif;*.bmp;*.ico;*.tif;*.tiff;*.webp|Video files (*.webm)|*.webm|All files (*.*)|*.*"
+	dlg.Filter = "Image files (*.jpg;*.jpeg;*.png;*.gif;*.bmp;*.ico;*.tif;*.tiff;*.webp)|*.jpg;*.jpeg;*.png;*.gif;*.bmp;*.ico;*.tif;*.tiff;*.webp|Video files (*.webm)|*.webm|Text files (*.txt;*.log)|*.txt;*.log|All files (*.*)|*.*"
 
 	if ok, err := dlg.ShowOpenMultiple(a.mainWindow); err != nil {
 		showError(fmt.Sprintf("Failed to open file dialog: %v", err))
@@ -429,7 +809,14 @@ func (a *App) onSelectFiles() {
 		return
 	}
 
-	for _, path := range dlg.FilePaths {
+	a.addFiles(dlg.FilePaths)
+}
+
+func (a *App) addFiles(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	for _, path := range paths {
 		a.selectedFiles = append(a.selectedFiles, path)
 		a.fileListModel.items = append(a.fileListModel.items, FileItem{Path: path, Base: filepath.Base(path)})
 	}
@@ -472,6 +859,75 @@ func (a *App) onRemoveSelected() {
 	a.fileListModel.PublishItemsReset()
 }
 
+// onEditFileDescription sets the Image Chest description of the activated
+// file. Files without one fall back to a sidecar .txt at upload time.
+func (a *App) onEditFileDescription() {
+	i := a.fileListBox.CurrentIndex()
+	if !a.selected(imgchestProvider{}) || i < 0 || i >= len(a.fileListModel.items) {
+		return
+	}
+	item := &a.fileListModel.items[i]
+	desc, ok := promptText(a.mainWindow, "Description for "+item.Base, firstNonEmpty(item.Desc, imgchestSidecarDescription(item.Path)))
+	if !ok {
+		return
+	}
+	item.Desc = desc
+	a.fileListModel.PublishItemChanged(i)
+}
+
+// promptText asks for a single line of text and reports whether OK was chosen.
+func promptText(owner walk.Form, title, text string) (string, bool) {
+	var dlg *walk.Dialog
+	var edit *walk.LineEdit
+	var okButton, cancelButton *walk.PushButton
+	err := Dialog{
+		AssignTo:      &dlg,
+		Title:         title,
+		MinSize:       Size{Width: 380, Height: 120},
+		Layout:        VBox{Margins: Margins{Left: 12, Top: 12, Right: 12, Bottom: 12}, Spacing: 8},
+		DefaultButton: &okButton,
+		CancelButton:  &cancelButton,
+		Children: []Widget{
+			LineEdit{AssignTo: &edit, Text: text},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					HSpacer{},
+					PushButton{AssignTo: &okButton, Text: "OK", OnClicked: func() { dlg.Accept() }},
+					PushButton{AssignTo: &cancelButton, Text: "Cancel", OnClicked: func() { dlg.Cancel() }},
+				},
+			},
+		},
+	}.Create(owner)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open dialog: %v", err))
+		return "", false
+	}
+	if IsSystemDarkMode() {
+		SetDarkModeTitleBar(uintptr(dlg.Handle()), true)
+		brush, _ := walk.NewSolidColorBrush(darkTheme.WindowBG)
+		dlg.SetBackground(brush)
+		applyDarkToLineEdit(edit)
+		applyDarkToButton(okButton)
+		applyDarkToButton(cancelButton)
+		installDarkThemeWndProcFor(dlg.Handle())
+	}
+	if dlg.Run() != walk.DlgCmdOK {
+		return "", false
+	}
+	return strings.TrimSpace(edit.Text()), true
+}
+
+func (a *App) imageDescriptions() map[string]string {
+	descriptions := make(map[string]string)
+	for _, item := range a.fileListModel.items {
+		if item.Desc != "" {
+			descriptions[item.Path] = item.Desc
+		}
+	}
+	return descriptions
+}
+
 func (a *App) onClearAll() {
 	if len(a.selectedFiles) == 0 {
 		return
@@ -486,6 +942,7 @@ func (a *App) hideCopyButton() {
 	a.copiedLinks = nil
 	if a.copyButton != nil {
 		a.copyButton.SetVisible(false)
+		a.shortenButton.SetVisible(false)
 	}
 }
 
@@ -500,104 +957,299 @@ func (a *App) onCopyLinks() {
 	a.outputEdit.AppendText("\r\n✓ Copied!\r\n")
 }
 
+func (a *App) onShortenLinks() {
+	links := append([]string(nil), a.copiedLinks...)
+	if len(links) == 0 {
+		return
+	}
+	ctx, ok := a.startCancellable()
+	if !ok {
+		return
+	}
+	a.shortenButton.SetEnabled(false)
+	a.outputEdit.AppendText("\r\nShortening links...\r\n")
+	go func() {
+		items, errors := shortenLinks(ctx, links)
+		historyErr := recordShortLinks(items)
+		a.mainWindow.Synchronize(func() {
+			a.shortenButton.SetEnabled(true)
+			a.finishUpload()
+			var output strings.Builder
+			short := make(map[string]string, len(items))
+			for _, item := range items {
+				short[item.Source] = item.URL
+				output.WriteString(fmt.Sprintf("%s → %s\r\n", item.URL, item.Source))
+			}
+			for _, e := range errors {
+				output.WriteString("Error: " + e + "\r\n")
+			}
+			if historyErr != nil {
+				output.WriteString(fmt.Sprintf("Warning: failed to record history: %v\r\n", historyErr))
+			}
+			a.outputEdit.AppendText(output.String())
+			if len(short) > 0 {
+				for i, link := range a.copiedLinks {
+					if u, ok := short[link]; ok {
+						a.copiedLinks[i] = u
+					}
+				}
+				a.shortenButton.SetVisible(false)
+			}
+		})
+	}()
+}
+
+// resultFileID returns the Image Chest image linked on the results line under
+// the caret.
+func (a *App) resultFileID() (string, bool) {
+	start, _ := a.outputEdit.TextSelection()
+	id := imgchestFileIDAt(a.outputEdit.Text(), start)
+	if id == "" {
+		showError("Click an Image Chest image link in the results first")
+		return "", false
+	}
+	return id, true
+}
+
+// runResultAction runs op like an upload, so Cancel stops it, and appends its
+// message or error to the results.
+func (a *App) runResultAction(status string, op func(ctx context.Context) (string, error)) {
+	ctx, ok := a.startCancellable()
+	if !ok {
+		return
+	}
+	a.applyCredentials()
+	a.outputEdit.AppendText("\r\n" + status + "\r\n")
+	go func() {
+		message, err := op(ctx)
+		a.mainWindow.Synchronize(func() {
+			cancelled := ctx.Err() != nil
+			a.finishUpload()
+			switch {
+			case cancelled:
+				a.outputEdit.AppendText("Cancelled\r\n")
+			case err != nil:
+				a.outputEdit.AppendText("Error: " + err.Error() + "\r\n")
+			default:
+				a.outputEdit.AppendText(message + "\r\n")
+			}
+		})
+	}()
+}
+
+func (a *App) onResultFileInfo() {
+	id, ok := a.resultFileID()
+	if !ok {
+		return
+	}
+	a.runResultAction("Fetching image "+id+"...", func(ctx context.Context) (string, error) {
+		img, err := getImgchestFile(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s\r\nPosition: %d\r\nName: %s\r\nCreated: %s\r\nDescription: %s",
+			img.Link, img.Position, img.OriginalName, img.Created, img.Description), nil
+	})
+}
+
+func (a *App) onResultEditDescription() {
+	id, ok := a.resultFileID()
+	if !ok {
+		return
+	}
+	desc, ok := promptText(a.mainWindow, "Description for image "+id, "")
+	if !ok {
+		return
+	}
+	a.runResultAction("Updating image "+id+"...", func(ctx context.Context) (string, error) {
+		return "✓ Description updated", updateImgchestFile(ctx, id, desc)
+	})
+}
+
+func (a *App) onResultDeleteImage() {
+	id, ok := a.resultFileID()
+	if !ok {
+		return
+	}
+	message := fmt.Sprintf("Delete image %s from its post? This cannot be undone.", id)
+	if walk.MsgBox(a.mainWindow, "Image Chest", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return
+	}
+	a.runResultAction("Deleting image "+id+"...", func(ctx context.Context) (string, error) {
+		return "✓ Image deleted", deleteImgchestFile(ctx, id)
+	})
+}
+
 func (a *App) onUpload() {
 	if len(a.selectedFiles) == 0 && a.urlEdit.Text() == "" {
 		showError("Please select files or enter URLs to upload")
 		return
 	}
 
+	ctx, ok := a.startCancellable()
+	if !ok {
+		return
+	}
+
 	acquired, err := TryAcquireUploadLock()
 	if err != nil {
 		showError(fmt.Sprintf("Failed to acquire upload lock: %v", err))
+		a.finishUpload()
 		return
 	}
 
-	a.uploadButton.SetEnabled(false)
-
 	if !acquired {
 		a.outputEdit.SetText("Waiting for another upload to complete...\r\n")
 		go func() {
-			err := AcquireUploadLock()
+			err := AcquireUploadLock(ctx)
 			if err != nil {
 				a.mainWindow.Synchronize(func() {
-					a.outputEdit.SetText(fmt.Sprintf("Failed to acquire lock: %v", err))
-					a.uploadButton.SetEnabled(true)
+					if ctx.Err() != nil {
+						a.outputEdit.SetText("Cancelled\r\n")
+					} else {
+						a.outputEdit.SetText(fmt.Sprintf("Failed to acquire lock: %v", err))
+					}
+					a.finishUpload()
 				})
 				return
 			}
 			a.mainWindow.Synchronize(func() {
-				a.startUpload()
+				a.startUpload(ctx)
 			})
 		}()
 		return
 	}
 
-	a.startUpload()
+	a.startUpload(ctx)
+}
+
+// startCancellable begins a network operation that the Cancel button and
+// closing the window stop. It fails while another one is still running.
+func (a *App) startCancellable() (context.Context, bool) {
+	if a.cancelUpload != nil {
+		showError("Wait for the current operation to finish")
+		return nil, false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancelUpload = cancel
+	a.uploadButton.SetEnabled(false)
+	a.cancelButton.SetEnabled(true)
+	a.cancelButton.SetVisible(true)
+	return ctx, true
 }
 
-func (a *App) startUpload() {
+func (a *App) onCancel() {
+	if a.cancelUpload == nil {
+		return
+	}
+	a.cancelUpload()
+	a.cancelButton.SetEnabled(false)
+	a.outputEdit.AppendText("\r\nCancelling...\r\n")
+}
+
+func (a *App) finishUpload() {
+	if a.cancelUpload != nil {
+		a.cancelUpload()
+		a.cancelUpload = nil
+	}
+	a.cancelButton.SetVisible(false)
+	a.uploadButton.SetEnabled(true)
+}
+
+func (a *App) applyCredentials() {
+	SetCatboxUserhash(strings.TrimSpace(a.catboxUserhashEdit.Text()))
+	SetImgchestToken(strings.TrimSpace(a.imgchestTokenEdit.Text()))
+	SetKekAPIKey(strings.TrimSpace(a.kekApiKeyEdit.Text()))
+	SetSxcuSubdomain(strings.TrimSpace(a.sxcuSubdomainEdit.Text()), strings.TrimSpace(a.sxcuUploadTokenEdit.Text()))
+}
+
+func (a *App) uploadJob() *UploadJob {
+	return &UploadJob{
+		Files:               append([]string(nil), a.selectedFiles...),
+		URLs:                splitURLList(a.urlEdit.Text()),
+		Title:               a.titleEdit.Text(),
+		Desc:                a.descEdit.Text(),
+		CreateAlbum:         a.albumCheck.Checked(),
+		AlbumShort:          catboxAlbumShort(a.albumShortEdit.Text()),
+		LitterboxExpiry:     a.litterboxExpiry(),
+		CreateCollection:    a.collectionCheck.Checked(),
+		SxcuCollectionID:    sxcuCollectionID(a.sxcuCollectionEdit.Text()),
+		SxcuCollectionToken: strings.TrimSpace(a.sxcuCollTokenEdit.Text()),
+		SxcuCollection: SxcuCollectionOptions{
+			Private:  a.sxcuPrivateCheck.Checked(),
+			Unlisted: true,
+		},
+		SxcuOG:           a.sxcuOG,
+		SxcuSelfDestruct: a.selfDestructCheck.Checked(),
+		SxcuPaste:        a.sxcuPasteCheck.Checked(),
+		Imgchest: ImgchestUploadOptions{
+			Privacy:   strings.ToLower(a.privacyCombo.Text()),
+			NSFW:      a.nsfwCheck.Checked(),
+			Anonymous: a.anonymousCheck.Checked(),
+		},
+		ImageDescriptions: a.imageDescriptions(),
+		PostID:            a.postIDEdit.Text(),
+		KekMature:         a.kekMatureCheck.Checked(),
+		KekVisibility:     a.kekVisibility(),
+	}
+}
+
+func (a *App) startUpload(ctx context.Context) {
 	a.hideCopyButton()
 	a.outputEdit.SetText("Starting upload...\r\n")
 
+	provider := a.providerCombo.Text()
+	a.applyCredentials()
+	job := a.uploadJob()
+
 	go func() {
 		defer ReleaseUploadLock()
 
-		provider := a.providerCombo.Text()
-		title := a.titleEdit.Text()
-		desc := a.descEdit.Text()
-
-		var results []string
-		var groupResult string
-		var errors []string
-		var successCount int
-
+		statusText, progressLine := "Starting upload...\r\n", ""
+		render := func() {
+			if progressLine == "" {
+				a.outputEdit.SetText(statusText)
+				return
+			}
+			status, rest, _ := strings.Cut(statusText, "\r\n")
+			a.outputEdit.SetText(status + "\r\n" + progressLine + "\r\n" + rest)
+		}
 		updateOutput := func(text string) {
 			a.mainWindow.Synchronize(func() {
-				a.outputEdit.SetText(text)
+				statusText = text
+				render()
+			})
+		}
+		onProgress := func(p UploadProgress) {
+			line := formatProgress(p)
+			a.mainWindow.Synchronize(func() {
+				progressLine = line
+				render()
 			})
 		}
+		ctx := withProgressTracker(ctx, newProgressTracker(totalFileSize(job.Files), onProgress))
 
-		switch provider {
-		case "catbox":
-			urls := a.urlEdit.Text()
-			createAlbum := a.albumCheck.Checked()
-			results, groupResult, errors = a.uploadCatbox(urls, title, desc, createAlbum)
-			successCount = len(results)
-
-		case "sxcu":
-			createCollection := a.collectionCheck.Checked()
-			sxcuOpts := SxcuCollectionOptions{
-				Private:  a.sxcuPrivateCheck.Checked(),
-				Unlisted: true,
-			}
-			results, groupResult, errors = a.uploadSxcu(title, desc, createCollection, sxcuOpts, updateOutput)
-			successCount = len(results)
-
-		case "imgchest":
-			postID := a.postIDEdit.Text()
-			customToken := strings.TrimSpace(a.imgchestTokenEdit.Text())
-			SetImgchestToken(customToken)
-			opts := ImgchestUploadOptions{
-				Title:     title,
-				Privacy:   strings.ToLower(a.privacyCombo.Text()),
-				NSFW:      a.nsfwCheck.Checked(),
-				Anonymous: a.anonymousCheck.Checked(),
-			}
-			results, groupResult, errors, successCount = a.uploadImgchest(opts, postID, updateOutput)
-
-		case "kek":
-			urls := a.urlEdit.Text()
-			SetKekAPIKey(strings.TrimSpace(a.kekApiKeyEdit.Text()))
-			mature := a.kekMatureCheck.Checked()
-			results, errors = a.uploadKek(urls, mature, updateOutput)
-			successCount = len(results)
+		var summary UploadSummary
+		if p, ok := LookupProvider(provider); ok {
+			summary = p.Upload(ctx, job, updateOutput)
+		} else {
+			summary.Errors = []string{fmt.Sprintf("unknown provider %q", provider)}
 		}
+		historyErr := recordUploadHistory(provider, job, summary)
+		results := summary.Results
+		groupResult := summary.GroupResult
+		errors := summary.Errors
+		details := summary.Details()
+		successCount := summary.SuccessCount
+		cancelled := summary.Cancelled
 
 		a.mainWindow.Synchronize(func() {
 			var output strings.Builder
 			output.Grow(2048)
 
-			if len(errors) > 0 {
+			if cancelled {
+				output.WriteString(fmt.Sprintf("Cancelled: %d uploaded before cancellation\r\n\r\n", successCount))
+			} else if len(errors) > 0 {
 				output.WriteString(fmt.Sprintf("Done: %d success, %d failed\r\n\r\n", successCount, len(errors)))
 			} else {
 				output.WriteString(fmt.Sprintf("Done: %d uploaded\r\n\r\n", successCount))
@@ -613,6 +1265,15 @@ func (a *App) startUpload() {
 				output.WriteString("\r\n")
 			}
 
+			if len(details) > 0 {
+				output.WriteString("\r\nDeletion and thumbnail links:\r\n")
+				for _, d := range details {
+					output.WriteString("• ")
+					output.WriteString(d)
+					output.WriteString("\r\n")
+				}
+			}
+
 			if len(errors) > 0 {
 				output.WriteString("\r\nErrors:\r\n")
 				for _, e := range errors {
@@ -622,379 +1283,19 @@ func (a *App) startUpload() {
 				}
 			}
 
+			if historyErr != nil {
+				output.WriteString(fmt.Sprintf("\r\nWarning: failed to record history: %v\r\n", historyErr))
+			}
+
 			a.outputEdit.SetText(output.String())
-			a.uploadButton.SetEnabled(true)
+			a.finishUpload()
 
 			if successCount > 0 {
 				a.uploadCompleted = true
 				a.copiedLinks = append([]string(nil), results...)
 				a.copyButton.SetVisible(true)
+				a.shortenButton.SetVisible(true)
 			}
 		})
 	}()
 }
-
-func (a *App) uploadCatbox(urls, title, desc string, createAlbum bool) ([]string, string, []string) {
-	totalFiles := len(a.selectedFiles)
-	results := make([]string, 0, totalFiles)
-	errors := make([]string, 0, 4)
-	uploadedFilenames := make([]string, 0, totalFiles)
-	var albumResult string
-
-	for _, filePath := range a.selectedFiles {
-		url, err := uploadFileToCatbox(filePath)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", filepath.Base(filePath), err))
-		} else {
-			results = append(results, url)
-			uploadedFilenames = append(uploadedFilenames, extractCatboxFilename(url))
-		}
-	}
-
-	if urls != "" {
-		for _, u := range strings.Split(urls, ",") {
-			u = strings.TrimSpace(u)
-			if u == "" {
-				continue
-			}
-			url, err := uploadURLToCatbox(u)
-			if err != nil {
-				errors = append(errors, fmt.Sprintf("URL %s: %v", u, err))
-			} else {
-				results = append(results, url)
-				uploadedFilenames = append(uploadedFilenames, extractCatboxFilename(url))
-			}
-		}
-	}
-
-	if createAlbum && len(uploadedFilenames) > 0 {
-		albumURL, err := createCatboxAlbum(uploadedFilenames, title, desc)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Album creation: %v", err))
-		} else {
-			albumResult = "Album: " + albumURL
-		}
-	}
-
-	return results, albumResult, errors
-}
-
-func (a *App) uploadKek(urls string, mature bool, updateOutput func(string)) ([]string, []string) {
-	apiKey, err := getKekAPIKey()
-	if err != nil {
-		return nil, []string{err.Error()}
-	}
-
-	urlValues := make([]string, 0, 4)
-	if urls != "" {
-		for _, u := range strings.Split(urls, ",") {
-			u = strings.TrimSpace(u)
-			if u != "" {
-				urlValues = append(urlValues, u)
-			}
-		}
-	}
-
-	totalItems := len(a.selectedFiles) + len(urlValues)
-	results := make([]string, 0, totalItems)
-	errors := make([]string, 0, 4)
-
-	buildOutput := func() string {
-		var output strings.Builder
-		output.Grow(2048)
-		if len(errors) > 0 {
-			output.WriteString(fmt.Sprintf("Uploading... %d/%d (%d failed)\r\n\r\n", len(results), totalItems, len(errors)))
-		} else {
-			output.WriteString(fmt.Sprintf("Uploading... %d/%d\r\n\r\n", len(results), totalItems))
-		}
-		for _, r := range results {
-			output.WriteString(r)
-			output.WriteString("\r\n")
-		}
-		for _, e := range errors {
-			output.WriteString("Error: ")
-			output.WriteString(e)
-			output.WriteString("\r\n")
-		}
-		return output.String()
-	}
-
-	setMature := func(label string, resp *KekPostResponse) {
-		postID := resp.GetID()
-		if postID == "" {
-			errors = append(errors, fmt.Sprintf("%s maturity: missing post ID", label))
-			return
-		}
-		if err := setKekPostMature(postID, apiKey, mature); err != nil {
-			errors = append(errors, fmt.Sprintf("%s maturity: %v", label, err))
-		}
-	}
-
-	for _, filePath := range a.selectedFiles {
-		resp, err := uploadFileToKek(filePath, apiKey)
-		label := filepath.Base(filePath)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", label, err))
-		} else {
-			if postURL := resp.GetURL(); postURL != "" {
-				results = append(results, postURL)
-			} else {
-				results = append(results, resp.GetID())
-			}
-			setMature(label, resp)
-		}
-		updateOutput(buildOutput())
-	}
-
-	for _, u := range urlValues {
-		resp, err := uploadURLToKek(u, apiKey)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("URL %s: %v", u, err))
-		} else {
-			if postURL := resp.GetURL(); postURL != "" {
-				results = append(results, postURL)
-			} else {
-				results = append(results, resp.GetID())
-			}
-			setMature("URL "+u, resp)
-		}
-		updateOutput(buildOutput())
-	}
-
-	return results, errors
-}
-
-func (a *App) uploadSxcu(title, desc string, createCollection bool, opts SxcuCollectionOptions, updateOutput func(string)) ([]string, string, []string) {
-	totalFiles := len(a.selectedFiles)
-	results := make([]string, 0, totalFiles)
-	errors := make([]string, 0, 4)
-	var collectionResult string
-	var collectionID string
-	var collectionToken string
-	var rateLimitStatus string
-
-	buildOutput := func() string {
-		var output strings.Builder
-		output.Grow(2048)
-		successCount := len(results)
-		failCount := len(errors)
-		if failCount > 0 {
-			output.WriteString(fmt.Sprintf("Uploading... %d/%d (%d failed)\r\n\r\n", successCount, totalFiles, failCount))
-		} else {
-			output.WriteString(fmt.Sprintf("Uploading... %d/%d\r\n\r\n", successCount, totalFiles))
-		}
-		if rateLimitStatus != "" {
-			output.WriteString(rateLimitStatus)
-			output.WriteString("\r\n")
-		}
-		if collectionResult != "" {
-			output.WriteString(collectionResult)
-			output.WriteString("\r\n")
-		}
-		for _, r := range results {
-			output.WriteString(r)
-			output.WriteString("\r\n")
-		}
-		for _, e := range errors {
-			output.WriteString("Error: ")
-			output.WriteString(e)
-			output.WriteString("\r\n")
-		}
-		return output.String()
-	}
-
-	waitWithCountdown := func(waitMs int64, bucket string) {
-		friendlyBucket := bucket
-		switch bucket {
-		case "__sxcu_file_upload__":
-			friendlyBucket = "file upload"
-		case "__sxcu_collection__":
-			friendlyBucket = "collection"
-		case "__sxcu_global__":
-			friendlyBucket = "global"
-		}
-		endTime := timeNow().Add(time.Duration(waitMs) * time.Millisecond)
-		for {
-			remaining := endTime.Sub(timeNow())
-			if remaining <= 0 {
-				break
-			}
-			secs := int(remaining.Seconds())
-			if secs >= 60 {
-				rateLimitStatus = fmt.Sprintf("⏳ Rate limited (%s): %dm %ds remaining...", friendlyBucket, secs/60, secs%60)
-			} else {
-				rateLimitStatus = fmt.Sprintf("⏳ Rate limited (%s): %ds remaining...", friendlyBucket, secs)
-			}
-			updateOutput(buildOutput())
-			sleepDuration := 500 * time.Millisecond
-			if remaining < sleepDuration {
-				sleepDuration = remaining
-			}
-			timeSleep(sleepDuration)
-		}
-		rateLimitStatus = ""
-	}
-
-	if createCollection && len(a.selectedFiles) > 0 {
-		collTitle := title
-		if collTitle == "" {
-			collTitle = "Untitled"
-		}
-		coll, err := createSxcuCollection(collTitle, desc, opts, 5)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Collection creation: %v", err))
-		} else {
-			collectionID = coll.CollectionID
-			collectionToken = coll.CollectionToken
-			collectionResult = "Collection: " + coll.GetURL()
-		}
-		updateOutput(buildOutput())
-	}
-
-	for _, filePath := range a.selectedFiles {
-		for {
-			check := checkSxcuRateLimit(sxcuFileUploadBucket)
-			if check.Allowed {
-				break
-			}
-			waitWithCountdown(check.WaitMs, check.Bucket)
-		}
-		resp, err := uploadFileToSxcuWithRateLimitInfo(filePath, collectionID, collectionToken, 5, func(waitMs int64, bucket string) {
-			waitWithCountdown(waitMs, bucket)
-		})
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", filepath.Base(filePath), err))
-		} else {
-			results = append(results, resp.URL)
-		}
-		updateOutput(buildOutput())
-	}
-
-	return results, collectionResult, errors
-}
-
-func (a *App) uploadImgchest(opts ImgchestUploadOptions, postID string, updateOutput func(string)) ([]string, string, []string, int) {
-	if len(a.selectedFiles) == 0 {
-		return nil, "", nil, 0
-	}
-
-	validFiles := make([]string, 0, len(a.selectedFiles))
-	errors := make([]string, 0, 4)
-
-	for _, filePath := range a.selectedFiles {
-		if err := ValidateImgchestFile(filePath); err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", filepath.Base(filePath), err))
-		} else {
-			validFiles = append(validFiles, filePath)
-		}
-	}
-
-	if len(validFiles) == 0 {
-		return nil, "", errors, 0
-	}
-
-	totalFiles := len(validFiles)
-	results := make([]string, 0, totalFiles)
-	var postResult string
-	allImageIDs := make([]string, 0, totalFiles)
-
-	uploadedCount := 0
-	useUploadedCount := false
-
-	buildOutput := func() string {
-		var output strings.Builder
-		output.Grow(2048)
-		var successCount int
-		if useUploadedCount {
-			successCount = uploadedCount
-		} else {
-			successCount = len(results)
-		}
-		failCount := len(errors)
-		if failCount > 0 {
-			output.WriteString(fmt.Sprintf("Uploading... %d/%d (%d failed)\r\n\r\n", successCount, totalFiles, failCount))
-		} else {
-			output.WriteString(fmt.Sprintf("Uploading... %d/%d\r\n\r\n", successCount, totalFiles))
-		}
-		if postResult != "" {
-			output.WriteString(postResult)
-			output.WriteString("\r\n")
-		}
-		for _, r := range results {
-			output.WriteString(r)
-			output.WriteString("\r\n")
-		}
-		for _, e := range errors {
-			output.WriteString("Error: ")
-			output.WriteString(e)
-			output.WriteString("\r\n")
-		}
-		return output.String()
-	}
-
-	if postID != "" {
-		const batchSize = 20
-		totalBatches := (len(validFiles) + batchSize - 1) / batchSize
-		seenLinks := make(map[string]struct{}, totalFiles)
-		useUploadedCount = true
-
-		for batchNum := 1; batchNum <= totalBatches; batchNum++ {
-			start := (batchNum - 1) * batchSize
-			end := start + batchSize
-			if end > len(validFiles) {
-				end = len(validFiles)
-			}
-			batch := validFiles[start:end]
-
-			resp, err := addToImgchestPost(postID, batch, 3)
-			if err != nil {
-				errors = append(errors, fmt.Sprintf("Batch %d: %s", batchNum, err.Error()))
-			} else {
-				if postResult == "" {
-					postResult = "Post: " + resp.GetPostURL()
-				}
-				uploadedCount += len(batch)
-				for _, img := range resp.Data.Images {
-					if _, seen := seenLinks[img.Link]; !seen {
-						seenLinks[img.Link] = struct{}{}
-						results = append(results, img.Link)
-						allImageIDs = append(allImageIDs, img.ID)
-					}
-				}
-			}
-			updateOutput(buildOutput())
-		}
-
-		if err := updateImgchestPost(postID, opts, 3); err != nil {
-			errors = append(errors, fmt.Sprintf("Failed to update post settings: %v", err))
-			updateOutput(buildOutput())
-		}
-
-		return results, postResult, errors, uploadedCount
-	}
-
-	seenLinks := make(map[string]struct{}, totalFiles)
-	callback := func(batchNum int, totalBatches int, postURL string, imageLinks []string, imageIDs []string, err error) {
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Batch %d: %s", batchNum, err.Error()))
-		} else {
-			if postResult == "" && postURL != "" {
-				postResult = "Post: " + postURL
-			}
-			for i, link := range imageLinks {
-				if _, seen := seenLinks[link]; !seen {
-					seenLinks[link] = struct{}{}
-					results = append(results, link)
-					if i < len(imageIDs) {
-						allImageIDs = append(allImageIDs, imageIDs[i])
-					}
-				}
-			}
-		}
-		updateOutput(buildOutput())
-	}
-
-	uploadToImgchestWithCallback(validFiles, opts, 3, callback)
-
-	return results, postResult, errors, len(results)
-}
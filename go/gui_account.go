@@ -0,0 +1,175 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+type AccountPostTableModel struct {
+	walk.TableModelBase
+	items []ImgchestPost
+}
+
+func (m *AccountPostTableModel) RowCount() int {
+	return len(m.items)
+}
+
+func (m *AccountPostTableModel) Value(row, col int) interface{} {
+	if row < 0 || row >= len(m.items) {
+		return ""
+	}
+	p := m.items[row]
+	switch col {
+	case 0:
+		return firstNonEmpty(p.Title, "(untitled)")
+	case 1:
+		return p.Privacy
+	case 2:
+		return p.ImageCount
+	case 3:
+		return p.URL()
+	}
+	return ""
+}
+
+type accountDialog struct {
+	dialog       *walk.Dialog
+	table        *walk.TableView
+	statusLabel  *walk.Label
+	moreButton   *walk.PushButton
+	useButton    *walk.PushButton
+	closeButton  *walk.PushButton
+	model        *AccountPostTableModel
+	username     string
+	nextPage     int
+	selectedPost string
+
+	// ctx is cancelled when the dialog closes, abandoning network calls
+	// still running for it.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (a *App) onBrowsePosts() {
+	a.applyCredentials()
+
+	d := &accountDialog{model: &AccountPostTableModel{}, nextPage: 1}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+	err := Dialog{
+		AssignTo:      &d.dialog,
+		Title:         "Image Chest Posts",
+		MinSize:       Size{Width: 480, Height: 340},
+		Size:          Size{Width: 640, Height: 420},
+		Layout:        VBox{Margins: Margins{Left: 12, Top: 12, Right: 12, Bottom: 12}, Spacing: 8},
+		DefaultButton: &d.useButton,
+		CancelButton:  &d.closeButton,
+		Children: []Widget{
+			TableView{
+				AssignTo:         &d.table,
+				Model:            d.model,
+				OnItemActivated:  d.onUsePost,
+				AlternatingRowBG: true,
+				Columns: []TableViewColumn{
+					{Title: "Title", Width: 200},
+					{Title: "Privacy", Width: 65},
+					{Title: "Images", Width: 55},
+					{Title: "Link", Width: 240},
+				},
+			},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{AssignTo: &d.statusLabel},
+					HSpacer{},
+					PushButton{AssignTo: &d.moreButton, Text: "Load More", Enabled: false, OnClicked: d.loadMore},
+					PushButton{AssignTo: &d.useButton, Text: "Use Post", OnClicked: d.onUsePost},
+					PushButton{AssignTo: &d.closeButton, Text: "Close", OnClicked: func() { d.dialog.Cancel() }},
+				},
+			},
+		},
+	}.Create(a.mainWindow)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open posts: %v", err))
+		return
+	}
+
+	if IsSystemDarkMode() {
+		SetDarkModeTitleBar(uintptr(d.dialog.Handle()), true)
+		brush, _ := walk.NewSolidColorBrush(darkTheme.WindowBG)
+		d.dialog.SetBackground(brush)
+		for _, b := range []*walk.PushButton{d.moreButton, d.useButton, d.closeButton} {
+			applyDarkToButton(b)
+		}
+		setWindowTheme(d.table.Handle(), "DarkMode_Explorer")
+		d.table.SetAlternatingRowBG(false)
+		applyDarkToLabels(d.dialog)
+		installDarkThemeWndProcFor(d.dialog.Handle())
+	}
+
+	d.loadMore()
+	if d.dialog.Run() == walk.DlgCmdOK && d.selectedPost != "" {
+		a.postIDEdit.SetText(d.selectedPost)
+	}
+}
+
+// synchronize runs f on the UI thread unless the dialog has been closed.
+func (d *accountDialog) synchronize(f func()) {
+	if d.ctx.Err() != nil {
+		return
+	}
+	d.dialog.Synchronize(func() {
+		if d.ctx.Err() == nil {
+			f()
+		}
+	})
+}
+
+// loadMore looks up the account on first use, then appends the next page.
+func (d *accountDialog) loadMore() {
+	d.moreButton.SetEnabled(false)
+	d.statusLabel.SetText("Loading...")
+	go func() {
+		ctx := d.ctx
+		username := d.username
+		var err error
+		if username == "" {
+			var user *ImgchestUser
+			if user, err = getImgchestUser(ctx); err == nil {
+				username = user.DisplayName()
+			}
+		}
+		var posts []ImgchestPost
+		var more bool
+		if err == nil {
+			posts, more, err = listImgchestUserPosts(ctx, username, d.nextPage)
+		}
+		d.synchronize(func() {
+			if err != nil {
+				d.statusLabel.SetText("Error: " + err.Error())
+				d.moreButton.SetEnabled(d.username != "")
+				return
+			}
+			d.username = username
+			d.nextPage++
+			d.model.items = append(d.model.items, posts...)
+			d.model.PublishRowsReset()
+			d.moreButton.SetEnabled(more)
+			d.statusLabel.SetText(fmt.Sprintf("%s · %d posts", d.username, len(d.model.items)))
+		})
+	}()
+}
+
+func (d *accountDialog) onUsePost() {
+	i := d.table.CurrentIndex()
+	if i < 0 || i >= len(d.model.items) {
+		return
+	}
+	d.selectedPost = d.model.items[i].ID
+	d.dialog.Accept()
+}
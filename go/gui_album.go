@@ -0,0 +1,229 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+type albumDialog struct {
+	dialog       *walk.Dialog
+	shortEdit    *walk.LineEdit
+	titleEdit    *walk.LineEdit
+	descEdit     *walk.LineEdit
+	filesEdit    *walk.TextEdit
+	statusLabel  *walk.Label
+	addButton    *walk.PushButton
+	removeButton *walk.PushButton
+	editButton   *walk.PushButton
+	deleteButton *walk.PushButton
+	closeButton  *walk.PushButton
+
+	// ctx is cancelled when the dialog closes, abandoning network calls
+	// still running for it.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (a *App) onManageAlbum() {
+	a.applyCredentials()
+
+	var files []string
+	if a.selected(catboxProvider{}) {
+		for _, link := range a.copiedLinks {
+			files = append(files, extractCatboxFilename(link))
+		}
+	}
+
+	d := &albumDialog{}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+	err := Dialog{
+		AssignTo:     &d.dialog,
+		Title:        "Catbox Album",
+		MinSize:      Size{Width: 380, Height: 320},
+		Size:         Size{Width: 420, Height: 360},
+		Layout:       VBox{Margins: Margins{Left: 12, Top: 12, Right: 12, Bottom: 12}, Spacing: 8},
+		CancelButton: &d.closeButton,
+		Children: []Widget{
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{Text: "Short code:", MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+					LineEdit{AssignTo: &d.shortEdit, Text: a.albumShortEdit.Text()},
+				},
+			},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{Text: "Title:", MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+					LineEdit{AssignTo: &d.titleEdit, Text: a.titleEdit.Text()},
+				},
+			},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{Text: "Description:", MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+					LineEdit{AssignTo: &d.descEdit, Text: a.descEdit.Text()},
+				},
+			},
+			Label{Text: "Files (catbox file names or links, one per line):"},
+			TextEdit{
+				AssignTo: &d.filesEdit,
+				Text:     strings.Join(files, "\r\n"),
+				VScroll:  true,
+				MinSize:  Size{Height: 90},
+			},
+			Label{AssignTo: &d.statusLabel},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					PushButton{AssignTo: &d.addButton, Text: "Add Files", OnClicked: d.onAdd},
+					PushButton{AssignTo: &d.removeButton, Text: "Remove Files", OnClicked: d.onRemove},
+					PushButton{AssignTo: &d.editButton, Text: "Replace All", OnClicked: d.onEdit},
+					PushButton{AssignTo: &d.deleteButton, Text: "Delete Album", OnClicked: d.onDelete},
+					HSpacer{},
+					PushButton{AssignTo: &d.closeButton, Text: "Close", OnClicked: func() { d.dialog.Cancel() }},
+				},
+			},
+		},
+	}.Create(a.mainWindow)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open album dialog: %v", err))
+		return
+	}
+
+	if IsSystemDarkMode() {
+		SetDarkModeTitleBar(uintptr(d.dialog.Handle()), true)
+		brush, _ := walk.NewSolidColorBrush(darkTheme.WindowBG)
+		d.dialog.SetBackground(brush)
+		applyDarkToLineEdit(d.shortEdit)
+		applyDarkToLineEdit(d.titleEdit)
+		applyDarkToLineEdit(d.descEdit)
+		applyDarkToTextEdit(d.filesEdit)
+		for _, b := range []*walk.PushButton{d.addButton, d.removeButton, d.editButton, d.deleteButton, d.closeButton} {
+			applyDarkToButton(b)
+		}
+		applyDarkToLabels(d.dialog)
+		installDarkThemeWndProcFor(d.dialog.Handle())
+	}
+
+	d.dialog.Run()
+}
+
+func (d *albumDialog) fileNames() []string {
+	var names []string
+	for _, line := range strings.FieldsFunc(d.filesEdit.Text(), func(r rune) bool {
+		return r == '\r' || r == '\n' || r == ' ' || r == '\t'
+	}) {
+		if name := extractCatboxFilename(line); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (d *albumDialog) setBusy(busy bool) {
+	for _, b := range []*walk.PushButton{d.addButton, d.removeButton, d.editButton, d.deleteButton} {
+		b.SetEnabled(!busy)
+	}
+}
+
+func (d *albumDialog) run(status string, op func(ctx context.Context) (string, error)) {
+	d.setBusy(true)
+	d.statusLabel.SetText(status)
+	go func() {
+		result, err := op(d.ctx)
+		d.synchronize(func() {
+			d.setBusy(false)
+			if err != nil {
+				d.statusLabel.SetText("Error: " + err.Error())
+				return
+			}
+			d.statusLabel.SetText(result)
+		})
+	}()
+}
+
+// synchronize runs f on the UI thread unless the dialog has been closed.
+func (d *albumDialog) synchronize(f func()) {
+	if d.ctx.Err() != nil {
+		return
+	}
+	d.dialog.Synchronize(func() {
+		if d.ctx.Err() == nil {
+			f()
+		}
+	})
+}
+
+func (d *albumDialog) confirm(message string) bool {
+	return walk.MsgBox(d.dialog, "Catbox Album", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) == walk.DlgCmdYes
+}
+
+func (d *albumDialog) requireFiles() ([]string, bool) {
+	names := d.fileNames()
+	if len(names) == 0 {
+		d.statusLabel.SetText("Enter at least one file")
+		return nil, false
+	}
+	return names, true
+}
+
+func (d *albumDialog) onAdd() {
+	short := d.shortEdit.Text()
+	names, ok := d.requireFiles()
+	if !ok {
+		return
+	}
+	d.run("Adding files...", func(ctx context.Context) (string, error) {
+		albumURL, err := addToCatboxAlbum(ctx, short, names)
+		return fmt.Sprintf("✓ Added %d file(s): %s", len(names), albumURL), err
+	})
+}
+
+func (d *albumDialog) onRemove() {
+	short := d.shortEdit.Text()
+	names, ok := d.requireFiles()
+	if !ok {
+		return
+	}
+	d.run("Removing files...", func(ctx context.Context) (string, error) {
+		albumURL, err := removeFromCatboxAlbum(ctx, short, names)
+		return fmt.Sprintf("✓ Removed %d file(s): %s", len(names), albumURL), err
+	})
+}
+
+func (d *albumDialog) onEdit() {
+	short, title, desc := d.shortEdit.Text(), d.titleEdit.Text(), d.descEdit.Text()
+	names, ok := d.requireFiles()
+	if !ok {
+		return
+	}
+	if !d.confirm(fmt.Sprintf("Replace the title, description and files of album %s?\r\n\r\nFiles not listed will be removed from the album.", catboxAlbumShort(short))) {
+		return
+	}
+	d.run("Saving album...", func(ctx context.Context) (string, error) {
+		albumURL, err := editCatboxAlbum(ctx, short, title, desc, names)
+		return "✓ Saved: " + albumURL, err
+	})
+}
+
+func (d *albumDialog) onDelete() {
+	short := d.shortEdit.Text()
+	if catboxAlbumShort(short) == "" {
+		d.statusLabel.SetText("Enter the album short code")
+		return
+	}
+	if !d.confirm(fmt.Sprintf("Delete album %s? The files themselves are kept.", catboxAlbumShort(short))) {
+		return
+	}
+	d.run("Deleting album...", func(ctx context.Context) (string, error) {
+		return "✓ Album deleted", deleteCatboxAlbum(ctx, short)
+	})
+}
@@ -0,0 +1,214 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+type embedDialog struct {
+	dialog       *walk.Dialog
+	presetCombo  *walk.ComboBox
+	titleEdit    *walk.LineEdit
+	descEdit     *walk.LineEdit
+	colorEdit    *walk.LineEdit
+	siteNameEdit *walk.LineEdit
+	showURLCheck *walk.CheckBox
+	statusLabel  *walk.Label
+	saveButton   *walk.PushButton
+	deleteButton *walk.PushButton
+	okButton     *walk.PushButton
+	cancelButton *walk.PushButton
+	store        *OGPresetStore
+	presetNames  []string
+	props        SxcuOGProperties
+}
+
+func embedSummary(o SxcuOGProperties) string {
+	switch {
+	case o.IsZero():
+		return "sxcu default"
+	case o.Title != "" && o.Title != sxcuOGOmit:
+		return o.Title
+	default:
+		return "Custom"
+	}
+}
+
+func (a *App) onEditEmbed() {
+	store, err := OpenOGPresetStore()
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open embed presets: %v", err))
+		return
+	}
+	names, err := store.Names()
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	d := &embedDialog{store: store, presetNames: names, props: a.sxcuOG}
+	row := func(label string, edit **walk.LineEdit, text, tip string) Widget {
+		return Composite{
+			Layout: HBox{MarginsZero: true, Spacing: 6},
+			Children: []Widget{
+				Label{Text: label, MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+				LineEdit{AssignTo: edit, Text: text, ToolTipText: tip},
+			},
+		}
+	}
+
+	err = Dialog{
+		AssignTo:      &d.dialog,
+		Title:         "sxcu Embed",
+		MinSize:       Size{Width: 380, Height: 300},
+		Size:          Size{Width: 420, Height: 320},
+		Layout:        VBox{Margins: Margins{Left: 12, Top: 12, Right: 12, Bottom: 12}, Spacing: 8},
+		DefaultButton: &d.okButton,
+		CancelButton:  &d.cancelButton,
+		Children: []Widget{
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{Text: "Preset:", MinSize: Size{Width: 70}, MaxSize: Size{Width: 70}},
+					ComboBox{
+						AssignTo:              &d.presetCombo,
+						Editable:              true,
+						Model:                 names,
+						OnCurrentIndexChanged: d.onPresetChanged,
+					},
+					PushButton{AssignTo: &d.saveButton, Text: "Save", OnClicked: d.onSavePreset},
+					PushButton{AssignTo: &d.deleteButton, Text: "Delete", OnClicked: d.onDeletePreset},
+				},
+			},
+			row("Title:", &d.titleEdit, d.props.Title, ""),
+			row("Description:", &d.descEdit, d.props.Description, ""),
+			row("Color:", &d.colorEdit, d.props.Color, "Hex color such as #7289DA"),
+			row("Site name:", &d.siteNameEdit, d.props.SiteName, ""),
+			CheckBox{
+				AssignTo: &d.showURLCheck,
+				Text:     "Show the link in Discord embeds",
+				Checked:  d.props.ShowURL,
+			},
+			Label{Text: `Leave a field empty for the sxcu default, or enter "false" to omit it.`},
+			Label{AssignTo: &d.statusLabel},
+			VSpacer{},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					HSpacer{},
+					PushButton{AssignTo: &d.okButton, Text: "OK", OnClicked: d.onOK},
+					PushButton{AssignTo: &d.cancelButton, Text: "Cancel", OnClicked: func() { d.dialog.Cancel() }},
+				},
+			},
+		},
+	}.Create(a.mainWindow)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open embed dialog: %v", err))
+		return
+	}
+
+	if IsSystemDarkMode() {
+		SetDarkModeTitleBar(uintptr(d.dialog.Handle()), true)
+		brush, _ := walk.NewSolidColorBrush(darkTheme.WindowBG)
+		d.dialog.SetBackground(brush)
+		applyDarkToComboBox(d.presetCombo)
+		for _, e := range []*walk.LineEdit{d.titleEdit, d.descEdit, d.colorEdit, d.siteNameEdit} {
+			applyDarkToLineEdit(e)
+		}
+		applyDarkToCheckBox(d.showURLCheck)
+		for _, b := range []*walk.PushButton{d.saveButton, d.deleteButton, d.okButton, d.cancelButton} {
+			applyDarkToButton(b)
+		}
+		applyDarkToLabels(d.dialog)
+		installDarkThemeWndProcFor(d.dialog.Handle())
+	}
+
+	if d.dialog.Run() == walk.DlgCmdOK {
+		a.sxcuOG = d.props
+		a.sxcuEmbedLabel.SetText(embedSummary(a.sxcuOG))
+	}
+}
+
+func (d *embedDialog) current() SxcuOGProperties {
+	return SxcuOGProperties{
+		Title:       strings.TrimSpace(d.titleEdit.Text()),
+		Description: strings.TrimSpace(d.descEdit.Text()),
+		Color:       strings.TrimSpace(d.colorEdit.Text()),
+		SiteName:    strings.TrimSpace(d.siteNameEdit.Text()),
+		ShowURL:     d.showURLCheck.Checked(),
+	}
+}
+
+func (d *embedDialog) onPresetChanged() {
+	i := d.presetCombo.CurrentIndex()
+	if i < 0 || i >= len(d.presetNames) {
+		return
+	}
+	props, err := d.store.Get(d.presetNames[i])
+	if err != nil {
+		d.statusLabel.SetText(err.Error())
+		return
+	}
+	d.titleEdit.SetText(props.Title)
+	d.descEdit.SetText(props.Description)
+	d.colorEdit.SetText(props.Color)
+	d.siteNameEdit.SetText(props.SiteName)
+	d.showURLCheck.SetChecked(props.ShowURL)
+	d.statusLabel.SetText("")
+}
+
+func (d *embedDialog) reloadPresets(selected string) {
+	names, err := d.store.Names()
+	if err != nil {
+		d.statusLabel.SetText(err.Error())
+		return
+	}
+	d.presetNames = names
+	d.presetCombo.SetModel(names)
+	d.presetCombo.SetText(selected)
+}
+
+func (d *embedDialog) onSavePreset() {
+	name := strings.TrimSpace(d.presetCombo.Text())
+	if name == "" {
+		d.statusLabel.SetText("Enter a preset name")
+		return
+	}
+	if err := d.store.Save(name, d.current()); err != nil {
+		d.statusLabel.SetText("Error: " + err.Error())
+		return
+	}
+	d.reloadPresets(name)
+	d.statusLabel.SetText("✓ Saved preset " + name)
+}
+
+func (d *embedDialog) onDeletePreset() {
+	name := strings.TrimSpace(d.presetCombo.Text())
+	if name == "" {
+		return
+	}
+	if walk.MsgBox(d.dialog, "sxcu Embed", fmt.Sprintf("Delete preset %s?", name), walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+	if err := d.store.Delete(name); err != nil {
+		d.statusLabel.SetText("Error: " + err.Error())
+		return
+	}
+	d.reloadPresets("")
+	d.statusLabel.SetText("✓ Deleted preset " + name)
+}
+
+func (d *embedDialog) onOK() {
+	props := d.current()
+	if err := props.Validate(); err != nil {
+		d.statusLabel.SetText("Error: " + err.Error())
+		return
+	}
+	d.props = props
+	d.dialog.Accept()
+}
@@ -0,0 +1,378 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+type HistoryTableModel struct {
+	walk.TableModelBase
+	items []HistoryEntry
+}
+
+func (m *HistoryTableModel) RowCount() int {
+	return len(m.items)
+}
+
+func (m *HistoryTableModel) Value(row, col int) interface{} {
+	if row < 0 || row >= len(m.items) {
+		return ""
+	}
+	e := m.items[row]
+	switch col {
+	case 0:
+		return e.UploadedAt.Local().Format("2006-01-02 15:04")
+	case 1:
+		return e.Provider
+	case 2:
+		return e.Name()
+	case 3:
+		return e.URL
+	case 4:
+		return e.GroupURL
+	case 5:
+		return e.Status()
+	}
+	return ""
+}
+
+const (
+	historyAnyTime = iota
+	historyToday
+	historyLast7Days
+	historyLast30Days
+	historyOnDate
+)
+
+type historyDialog struct {
+	dialog        *walk.Dialog
+	searchEdit    *walk.LineEdit
+	providerCombo *walk.ComboBox
+	periodCombo   *walk.ComboBox
+	dateEdit      *walk.DateEdit
+	table         *walk.TableView
+	statusLabel   *walk.Label
+	copyButton    *walk.PushButton
+	groupButton   *walk.PushButton
+	shortenButton *walk.PushButton
+	deleteButton  *walk.PushButton
+	closeButton   *walk.PushButton
+	model         *HistoryTableModel
+	store         *HistoryStore
+
+	// ctx is cancelled when the dialog closes, abandoning network calls
+	// still running for it.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (a *App) onShowHistory() {
+	a.applyCredentials()
+
+	store, err := OpenHistoryStore()
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open history: %v", err))
+		return
+	}
+
+	h := &historyDialog{model: &HistoryTableModel{}, store: store}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	defer h.cancel()
+	providers := append([]string{"All"}, ProviderNames()...)
+
+	err = Dialog{
+		AssignTo:     &h.dialog,
+		Title:        "Upload History",
+		MinSize:      Size{Width: 520, Height: 360},
+		Size:         Size{Width: 720, Height: 460},
+		Layout:       VBox{Margins: Margins{Left: 12, Top: 12, Right: 12, Bottom: 12}, Spacing: 8},
+		CancelButton: &h.closeButton,
+		Children: []Widget{
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{Text: "Search:"},
+					LineEdit{
+						AssignTo:      &h.searchEdit,
+						OnTextChanged: h.refresh,
+					},
+					ComboBox{
+						AssignTo:              &h.providerCombo,
+						Model:                 providers,
+						CurrentIndex:          0,
+						OnCurrentIndexChanged: h.refresh,
+						MaxSize:               Size{Width: 90},
+					},
+					ComboBox{
+						AssignTo:              &h.periodCombo,
+						Model:                 []string{"Any time", "Today", "Last 7 days", "Last 30 days", "On date"},
+						CurrentIndex:          historyAnyTime,
+						OnCurrentIndexChanged: h.onPeriodChanged,
+						MaxSize:               Size{Width: 100},
+					},
+					DateEdit{
+						AssignTo:      &h.dateEdit,
+						Enabled:       false,
+						OnDateChanged: h.refresh,
+						MaxSize:       Size{Width: 110},
+					},
+				},
+			},
+			TableView{
+				AssignTo:         &h.table,
+				Model:            h.model,
+				MultiSelection:   true,
+				OnItemActivated:  h.onCopyLinks,
+				AlternatingRowBG: true,
+				Columns: []TableViewColumn{
+					{Title: "Date", Width: 110},
+					{Title: "Provider", Width: 65},
+					{Title: "Name", Width: 160},
+					{Title: "URL", Width: 220},
+					{Title: "Group", Width: 140},
+					{Title: "Status", Width: 60},
+				},
+			},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{AssignTo: &h.statusLabel},
+					HSpacer{},
+					PushButton{
+						AssignTo:  &h.copyButton,
+						Text:      "⧉ Copy Links",
+						OnClicked: h.onCopyLinks,
+					},
+					PushButton{
+						AssignTo:  &h.groupButton,
+						Text:      "⧉ Copy Group Link",
+						OnClicked: h.onCopyGroupLinks,
+					},
+					PushButton{
+						AssignTo:    &h.shortenButton,
+						Text:        "Shorten",
+						ToolTipText: "Create sxcu short links and copy them",
+						OnClicked:   h.onShorten,
+					},
+					PushButton{
+						AssignTo:  &h.deleteButton,
+						Text:      "Delete",
+						OnClicked: h.onDelete,
+					},
+					PushButton{
+						AssignTo:  &h.closeButton,
+						Text:      "Close",
+						OnClicked: func() { h.dialog.Cancel() },
+					},
+				},
+			},
+		},
+	}.Create(a.mainWindow)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open history: %v", err))
+		return
+	}
+
+	if IsSystemDarkMode() {
+		SetDarkModeTitleBar(uintptr(h.dialog.Handle()), true)
+		h.applyDarkTheme()
+	}
+
+	h.refresh()
+	h.dialog.Run()
+}
+
+func (h *historyDialog) applyDarkTheme() {
+	brush, _ := walk.NewSolidColorBrush(darkTheme.WindowBG)
+	h.dialog.SetBackground(brush)
+	applyDarkToLineEdit(h.searchEdit)
+	applyDarkToComboBox(h.providerCombo)
+	applyDarkToComboBox(h.periodCombo)
+	applyDarkToButton(h.copyButton)
+	applyDarkToButton(h.groupButton)
+	applyDarkToButton(h.shortenButton)
+	applyDarkToButton(h.deleteButton)
+	applyDarkToButton(h.closeButton)
+	setWindowTheme(h.table.Handle(), "DarkMode_Explorer")
+	h.table.SetAlternatingRowBG(false)
+	applyDarkToLabels(h.dialog)
+	installDarkThemeWndProcFor(h.dialog.Handle())
+}
+
+func (h *historyDialog) onPeriodChanged() {
+	h.dateEdit.SetEnabled(h.periodCombo.CurrentIndex() == historyOnDate)
+	h.refresh()
+}
+
+func (h *historyDialog) query() HistoryQuery {
+	q := HistoryQuery{Text: h.searchEdit.Text()}
+	if h.providerCombo.CurrentIndex() > 0 {
+		q.Provider = h.providerCombo.Text()
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	switch h.periodCombo.CurrentIndex() {
+	case historyToday:
+		q.Since = today
+	case historyLast7Days:
+		q.Since = today.AddDate(0, 0, -6)
+	case historyLast30Days:
+		q.Since = today.AddDate(0, 0, -29)
+	case historyOnDate:
+		d := h.dateEdit.Date()
+		q.Since = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+		q.Until = q.Since.AddDate(0, 0, 1)
+	}
+	return q
+}
+
+func (h *historyDialog) refresh() {
+	if h.table == nil {
+		return
+	}
+	entries, err := h.store.Search(h.query())
+	if err != nil {
+		h.statusLabel.SetText(err.Error())
+		return
+	}
+	h.model.items = entries
+	h.model.PublishRowsReset()
+	if len(entries) == 1 {
+		h.statusLabel.SetText("1 upload")
+	} else {
+		h.statusLabel.SetText(fmt.Sprintf("%d uploads", len(entries)))
+	}
+}
+
+func (h *historyDialog) selected() []HistoryEntry {
+	indexes := h.table.SelectedIndexes()
+	entries := make([]HistoryEntry, 0, len(indexes))
+	for _, i := range indexes {
+		if i >= 0 && i < len(h.model.items) {
+			entries = append(entries, h.model.items[i])
+		}
+	}
+	return entries
+}
+
+func (h *historyDialog) copyToClipboard(links []string) {
+	if len(links) == 0 {
+		return
+	}
+	if err := walk.Clipboard().SetText(strings.Join(links, "\r\n")); err != nil {
+		showError(fmt.Sprintf("Failed to copy links: %v", err))
+		return
+	}
+	if len(links) == 1 {
+		h.statusLabel.SetText("✓ Copied 1 link")
+	} else {
+		h.statusLabel.SetText(fmt.Sprintf("✓ Copied %d links", len(links)))
+	}
+}
+
+func (h *historyDialog) onCopyLinks() {
+	selected := h.selected()
+	links := make([]string, 0, len(selected))
+	for _, e := range selected {
+		links = append(links, e.URL)
+	}
+	h.copyToClipboard(links)
+}
+
+func (h *historyDialog) onCopyGroupLinks() {
+	seen := make(map[string]struct{})
+	var links []string
+	for _, e := range h.selected() {
+		if e.GroupURL == "" {
+			continue
+		}
+		if _, ok := seen[e.GroupURL]; !ok {
+			seen[e.GroupURL] = struct{}{}
+			links = append(links, e.GroupURL)
+		}
+	}
+	h.copyToClipboard(links)
+}
+
+// synchronize runs f on the UI thread unless the dialog has been closed.
+func (h *historyDialog) synchronize(f func()) {
+	if h.ctx.Err() != nil {
+		return
+	}
+	h.dialog.Synchronize(func() {
+		if h.ctx.Err() == nil {
+			f()
+		}
+	})
+}
+
+func (h *historyDialog) onDelete() {
+	var entries []HistoryEntry
+	for _, e := range h.selected() {
+		if e.DeletedAt == nil {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	message := fmt.Sprintf("Delete %d upload(s) from the provider? This cannot be undone.", len(entries))
+	if walk.MsgBox(h.dialog, "Upload History", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return
+	}
+
+	h.deleteButton.SetEnabled(false)
+	h.statusLabel.SetText("Deleting...")
+	go func() {
+		deleted, errors := deleteHistoryEntries(h.ctx, h.store, entries)
+		h.synchronize(func() {
+			h.deleteButton.SetEnabled(true)
+			h.refresh()
+			if len(errors) > 0 {
+				h.statusLabel.SetText(fmt.Sprintf("Deleted %d, failed: %s", len(deleted), strings.Join(errors, "; ")))
+			} else {
+				h.statusLabel.SetText(fmt.Sprintf("✓ Deleted %d upload(s)", len(deleted)))
+			}
+		})
+	}()
+}
+
+func (h *historyDialog) onShorten() {
+	selected := h.selected()
+	links := make([]string, 0, len(selected))
+	for _, e := range selected {
+		links = append(links, e.URL)
+	}
+	if len(links) == 0 {
+		return
+	}
+
+	h.shortenButton.SetEnabled(false)
+	h.statusLabel.SetText("Shortening...")
+	go func() {
+		items, errors := shortenLinks(h.ctx, links)
+		if err := recordShortLinks(items); err != nil {
+			errors = append(errors, fmt.Sprintf("History: %v", err))
+		}
+		h.synchronize(func() {
+			h.shortenButton.SetEnabled(true)
+			h.refresh()
+			short := make([]string, 0, len(items))
+			for _, item := range items {
+				short = append(short, item.URL)
+			}
+			h.copyToClipboard(short)
+			if len(errors) > 0 {
+				h.statusLabel.SetText(fmt.Sprintf("Shortened %d, failed: %s", len(items), strings.Join(errors, "; ")))
+			}
+		})
+	}()
+}
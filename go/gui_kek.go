@@ -0,0 +1,313 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+type KekPostTableModel struct {
+	walk.TableModelBase
+	items []KekPost
+}
+
+func (m *KekPostTableModel) RowCount() int {
+	return len(m.items)
+}
+
+func (m *KekPostTableModel) Value(row, col int) interface{} {
+	if row < 0 || row >= len(m.items) {
+		return ""
+	}
+	p := m.items[row]
+	yesNo := func(b bool) string {
+		if b {
+			return "Yes"
+		}
+		return "No"
+	}
+	switch col {
+	case 0:
+		return p.GetID()
+	case 1:
+		return yesNo(p.Public)
+	case 2:
+		return yesNo(p.Mature)
+	case 3:
+		return p.CreatedAt
+	case 4:
+		return p.GetURL()
+	}
+	return ""
+}
+
+type kekDialog struct {
+	dialog        *walk.Dialog
+	table         *walk.TableView
+	statusLabel   *walk.Label
+	moreButton    *walk.PushButton
+	copyButton    *walk.PushButton
+	publicButton  *walk.PushButton
+	privateButton *walk.PushButton
+	deleteButton  *walk.PushButton
+	clearButton   *walk.PushButton
+	closeButton   *walk.PushButton
+	model         *KekPostTableModel
+	apiKey        string
+	more          bool
+
+	// ctx is cancelled when the dialog closes, abandoning network calls
+	// still running for it.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (a *App) onManageKekPosts() {
+	a.applyCredentials()
+	apiKey, err := getKekAPIKey()
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	d := &kekDialog{model: &KekPostTableModel{}, apiKey: apiKey}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+	err = Dialog{
+		AssignTo:     &d.dialog,
+		Title:        "kek Posts",
+		MinSize:      Size{Width: 520, Height: 340},
+		Size:         Size{Width: 680, Height: 420},
+		Layout:       VBox{Margins: Margins{Left: 12, Top: 12, Right: 12, Bottom: 12}, Spacing: 8},
+		CancelButton: &d.closeButton,
+		Children: []Widget{
+			TableView{
+				AssignTo:         &d.table,
+				Model:            d.model,
+				MultiSelection:   true,
+				OnItemActivated:  d.onCopyLinks,
+				AlternatingRowBG: true,
+				Columns: []TableViewColumn{
+					{Title: "ID", Width: 90},
+					{Title: "Public", Width: 55},
+					{Title: "Mature", Width: 55},
+					{Title: "Created", Width: 150},
+					{Title: "Link", Width: 240},
+				},
+			},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{AssignTo: &d.statusLabel},
+					HSpacer{},
+					PushButton{AssignTo: &d.moreButton, Text: "Load More", Enabled: false, OnClicked: d.loadMore},
+					PushButton{AssignTo: &d.copyButton, Text: "⧉ Copy Links", OnClicked: d.onCopyLinks},
+					PushButton{AssignTo: &d.publicButton, Text: "Make Public", OnClicked: func() { d.onSetPublic(true) }},
+					PushButton{AssignTo: &d.privateButton, Text: "Make Private", OnClicked: func() { d.onSetPublic(false) }},
+					PushButton{AssignTo: &d.deleteButton, Text: "Delete", OnClicked: d.onDelete},
+				},
+			},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					PushButton{AssignTo: &d.clearButton, Text: "Clear Storage…", OnClicked: d.onClearStorage},
+					HSpacer{},
+					PushButton{AssignTo: &d.closeButton, Text: "Close", OnClicked: func() { d.dialog.Cancel() }},
+				},
+			},
+		},
+	}.Create(a.mainWindow)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open kek posts: %v", err))
+		return
+	}
+
+	if IsSystemDarkMode() {
+		SetDarkModeTitleBar(uintptr(d.dialog.Handle()), true)
+		brush, _ := walk.NewSolidColorBrush(darkTheme.WindowBG)
+		d.dialog.SetBackground(brush)
+		for _, b := range append(d.buttons(), d.moreButton, d.closeButton) {
+			applyDarkToButton(b)
+		}
+		setWindowTheme(d.table.Handle(), "DarkMode_Explorer")
+		d.table.SetAlternatingRowBG(false)
+		applyDarkToLabels(d.dialog)
+		installDarkThemeWndProcFor(d.dialog.Handle())
+	}
+
+	d.reload("")
+	d.dialog.Run()
+}
+
+// synchronize runs f on the UI thread unless the dialog has been closed.
+func (d *kekDialog) synchronize(f func()) {
+	if d.ctx.Err() != nil {
+		return
+	}
+	d.dialog.Synchronize(func() {
+		if d.ctx.Err() == nil {
+			f()
+		}
+	})
+}
+
+func (d *kekDialog) buttons() []*walk.PushButton {
+	return []*walk.PushButton{d.copyButton, d.publicButton, d.privateButton, d.deleteButton, d.clearButton}
+}
+
+// fetch loads the page after the last listed post, or the first page when
+// replace is set, and shows status once it has loaded.
+func (d *kekDialog) fetch(replace bool, status string) {
+	from := ""
+	if !replace && len(d.model.items) > 0 {
+		from = d.model.items[len(d.model.items)-1].GetID()
+	}
+	d.setBusy(true)
+	d.statusLabel.SetText("Loading...")
+	go func() {
+		posts, err := listKekPosts(d.ctx, d.apiKey, from)
+		d.synchronize(func() {
+			d.setBusy(false)
+			if err != nil {
+				d.statusLabel.SetText("Error: " + err.Error())
+				return
+			}
+			if replace {
+				d.model.items = nil
+			}
+			d.model.items = append(d.model.items, posts...)
+			d.model.PublishRowsReset()
+			d.more = len(posts) >= kekPostsPageSize
+			d.moreButton.SetEnabled(d.more)
+			d.statusLabel.SetText(firstNonEmpty(status, fmt.Sprintf("%d posts", len(d.model.items))))
+		})
+	}()
+}
+
+func (d *kekDialog) reload(status string) {
+	d.fetch(true, status)
+}
+
+func (d *kekDialog) loadMore() {
+	d.fetch(false, "")
+}
+
+func (d *kekDialog) setBusy(busy bool) {
+	for _, b := range d.buttons() {
+		b.SetEnabled(!busy)
+	}
+	d.moreButton.SetEnabled(!busy && d.more)
+}
+
+func (d *kekDialog) selected() []KekPost {
+	indexes := d.table.SelectedIndexes()
+	posts := make([]KekPost, 0, len(indexes))
+	for _, i := range indexes {
+		if i >= 0 && i < len(d.model.items) {
+			posts = append(posts, d.model.items[i])
+		}
+	}
+	return posts
+}
+
+func (d *kekDialog) onCopyLinks() {
+	posts := d.selected()
+	if len(posts) == 0 {
+		return
+	}
+	links := make([]string, 0, len(posts))
+	for _, p := range posts {
+		links = append(links, p.GetURL())
+	}
+	if err := walk.Clipboard().SetText(strings.Join(links, "\r\n")); err != nil {
+		d.statusLabel.SetText("Error: " + err.Error())
+		return
+	}
+	d.statusLabel.SetText(fmt.Sprintf("✓ Copied %d link(s)", len(links)))
+}
+
+// each runs op for every selected post and reloads the list afterwards.
+func (d *kekDialog) each(status, done string, op func(ctx context.Context, id string) error) {
+	posts := d.selected()
+	if len(posts) == 0 {
+		return
+	}
+	d.setBusy(true)
+	d.statusLabel.SetText(status)
+	go func() {
+		var errs []string
+		for _, p := range posts {
+			if d.ctx.Err() != nil {
+				return
+			}
+			if err := op(d.ctx, p.GetID()); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", p.GetID(), err))
+			}
+		}
+		d.synchronize(func() {
+			if len(errs) > 0 {
+				walk.MsgBox(d.dialog, "kek Posts", strings.Join(errs, "\r\n"), walk.MsgBoxOK|walk.MsgBoxIconError)
+			}
+			d.reload(fmt.Sprintf("✓ %s %d of %d post(s)", done, len(posts)-len(errs), len(posts)))
+		})
+	}()
+}
+
+func (d *kekDialog) onSetPublic(public bool) {
+	done := "Made private"
+	if public {
+		done = "Made public"
+	}
+	d.each("Updating...", done, func(ctx context.Context, id string) error {
+		return setKekPostPublic(ctx, id, d.apiKey, public)
+	})
+}
+
+func (d *kekDialog) onDelete() {
+	n := len(d.selected())
+	if n == 0 {
+		return
+	}
+	message := fmt.Sprintf("Delete %d post(s)? This cannot be undone.", n)
+	if walk.MsgBox(d.dialog, "kek Posts", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return
+	}
+	d.each("Deleting...", "Deleted", func(ctx context.Context, id string) error {
+		return deleteKekPost(ctx, id, d.apiKey)
+	})
+}
+
+// onClearStorage asks twice, the second time for the word CLEAR, before
+// purging the whole account.
+func (d *kekDialog) onClearStorage() {
+	message := "Clear storage deletes EVERY post on this kek account, not only the ones listed here. This cannot be undone.\r\n\r\nContinue?"
+	if walk.MsgBox(d.dialog, "Clear kek Storage", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning|walk.MsgBoxDefButton2) != walk.DlgCmdYes {
+		return
+	}
+	typed, ok := promptText(d.dialog, "Type CLEAR to delete every post", "")
+	if !ok {
+		return
+	}
+	if typed != "CLEAR" {
+		d.statusLabel.SetText("Storage was not cleared")
+		return
+	}
+	d.setBusy(true)
+	d.statusLabel.SetText("Clearing storage...")
+	go func() {
+		err := clearKekStorage(d.ctx, d.apiKey)
+		d.synchronize(func() {
+			d.setBusy(false)
+			if err != nil {
+				d.statusLabel.SetText("Error: " + err.Error())
+				return
+			}
+			d.reload("✓ Storage cleared")
+		})
+	}()
+}
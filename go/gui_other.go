@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func runGUI(files []string) error {
+	return errors.New(`the GUI is only available on Windows; run "image-uploader help" for command-line usage`)
+}
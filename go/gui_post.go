@@ -0,0 +1,281 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+type PostImageTableModel struct {
+	walk.TableModelBase
+	items []ImgchestImage
+}
+
+func (m *PostImageTableModel) RowCount() int {
+	return len(m.items)
+}
+
+func (m *PostImageTableModel) Value(row, col int) interface{} {
+	if row < 0 || row >= len(m.items) {
+		return ""
+	}
+	img := m.items[row]
+	switch col {
+	case 0:
+		return img.Position
+	case 1:
+		return img.ID
+	case 2:
+		return img.Description
+	case 3:
+		return img.Link
+	}
+	return ""
+}
+
+type postDialog struct {
+	app            *App
+	postID         string
+	dialog         *walk.Dialog
+	infoLabel      *walk.Label
+	table          *walk.TableView
+	statusLabel    *walk.Label
+	refreshButton  *walk.PushButton
+	copyButton     *walk.PushButton
+	favoriteButton *walk.PushButton
+	deleteButton   *walk.PushButton
+	closeButton    *walk.PushButton
+	model          *PostImageTableModel
+
+	// ctx is cancelled when the dialog closes, abandoning network calls
+	// still running for it.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (a *App) onViewPost() {
+	postID := imgchestPostID(a.postIDEdit.Text())
+	if postID == "" {
+		return
+	}
+	a.applyCredentials()
+
+	d := &postDialog{app: a, postID: postID, model: &PostImageTableModel{}}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+	err := Dialog{
+		AssignTo:     &d.dialog,
+		Title:        "Image Chest Post " + postID,
+		MinSize:      Size{Width: 480, Height: 340},
+		Size:         Size{Width: 640, Height: 420},
+		Layout:       VBox{Margins: Margins{Left: 12, Top: 12, Right: 12, Bottom: 12}, Spacing: 8},
+		CancelButton: &d.closeButton,
+		Children: []Widget{
+			Label{AssignTo: &d.infoLabel, Text: "Loading..."},
+			TableView{
+				AssignTo:         &d.table,
+				Model:            d.model,
+				MultiSelection:   true,
+				AlternatingRowBG: true,
+				Columns: []TableViewColumn{
+					{Title: "#", Width: 35},
+					{Title: "ID", Width: 100},
+					{Title: "Description", Width: 220},
+					{Title: "Link", Width: 240},
+				},
+				ContextMenuItems: []MenuItem{
+					Action{Text: "Copy Link", OnTriggered: d.onCopyLinks},
+					Action{Text: "Edit Description…", OnTriggered: d.onEditDescription},
+					Action{Text: "Delete Image", OnTriggered: d.onDeleteImage},
+				},
+			},
+			Composite{
+				Layout: HBox{MarginsZero: true, Spacing: 6},
+				Children: []Widget{
+					Label{AssignTo: &d.statusLabel},
+					HSpacer{},
+					PushButton{AssignTo: &d.refreshButton, Text: "Refresh", OnClicked: d.load},
+					PushButton{AssignTo: &d.copyButton, Text: "⧉ Copy Links", OnClicked: d.onCopyLinks},
+					PushButton{AssignTo: &d.favoriteButton, Text: "☆ Favorite", OnClicked: d.onFavorite},
+					PushButton{AssignTo: &d.deleteButton, Text: "Delete Post", OnClicked: d.onDelete},
+					PushButton{AssignTo: &d.closeButton, Text: "Close", OnClicked: func() { d.dialog.Cancel() }},
+				},
+			},
+		},
+	}.Create(a.mainWindow)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to open post: %v", err))
+		return
+	}
+
+	if IsSystemDarkMode() {
+		SetDarkModeTitleBar(uintptr(d.dialog.Handle()), true)
+		brush, _ := walk.NewSolidColorBrush(darkTheme.WindowBG)
+		d.dialog.SetBackground(brush)
+		for _, b := range append(d.buttons(), d.closeButton) {
+			applyDarkToButton(b)
+		}
+		setWindowTheme(d.table.Handle(), "DarkMode_Explorer")
+		d.table.SetAlternatingRowBG(false)
+		applyDarkToLabels(d.dialog)
+		installDarkThemeWndProcFor(d.dialog.Handle())
+	}
+
+	d.load()
+	d.dialog.Run()
+}
+
+func (d *postDialog) buttons() []*walk.PushButton {
+	return []*walk.PushButton{d.refreshButton, d.copyButton, d.favoriteButton, d.deleteButton}
+}
+
+// synchronize runs f on the UI thread unless the dialog has been closed.
+func (d *postDialog) synchronize(f func()) {
+	if d.ctx.Err() != nil {
+		return
+	}
+	d.dialog.Synchronize(func() {
+		if d.ctx.Err() == nil {
+			f()
+		}
+	})
+}
+
+func (d *postDialog) run(status string, op func(ctx context.Context) (string, error), done func()) {
+	for _, b := range d.buttons() {
+		b.SetEnabled(false)
+	}
+	d.statusLabel.SetText(status)
+	go func() {
+		result, err := op(d.ctx)
+		d.synchronize(func() {
+			for _, b := range d.buttons() {
+				b.SetEnabled(true)
+			}
+			if err != nil {
+				d.statusLabel.SetText("Error: " + err.Error())
+				return
+			}
+			d.statusLabel.SetText(result)
+			if done != nil {
+				done()
+			}
+		})
+	}()
+}
+
+func (d *postDialog) load() {
+	d.reload("")
+}
+
+// reload fetches the post again and shows status once it has loaded.
+func (d *postDialog) reload(status string) {
+	var post *ImgchestPost
+	d.run("Loading...", func(ctx context.Context) (string, error) {
+		var err error
+		post, err = getImgchestPost(ctx, d.postID)
+		return status, err
+	}, func() {
+		title := post.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		nsfw := ""
+		if post.NSFW != 0 {
+			nsfw = " · NSFW"
+		}
+		d.infoLabel.SetText(fmt.Sprintf("%s\r\n%s · %d images · %d views%s · %s", title, post.Privacy, post.ImageCount, post.Views, nsfw, post.URL()))
+		d.model.items = post.Images
+		d.model.PublishRowsReset()
+	})
+}
+
+func (d *postDialog) onCopyLinks() {
+	indexes := d.table.SelectedIndexes()
+	var links []string
+	if len(indexes) == 0 {
+		for _, img := range d.model.items {
+			links = append(links, img.Link)
+		}
+	}
+	for _, i := range indexes {
+		if i >= 0 && i < len(d.model.items) {
+			links = append(links, d.model.items[i].Link)
+		}
+	}
+	if len(links) == 0 {
+		return
+	}
+	if err := walk.Clipboard().SetText(strings.Join(links, "\r\n")); err != nil {
+		d.statusLabel.SetText("Error: " + err.Error())
+		return
+	}
+	d.statusLabel.SetText(fmt.Sprintf("✓ Copied %d link(s)", len(links)))
+}
+
+func (d *postDialog) onFavorite() {
+	d.run("Updating favorites...", func(ctx context.Context) (string, error) {
+		message, err := favoriteImgchestPost(ctx, d.postID)
+		return "✓ " + firstNonEmpty(message, "Favorites updated"), err
+	}, nil)
+}
+
+func (d *postDialog) onDelete() {
+	message := fmt.Sprintf("Delete post %s and all of its images? This cannot be undone.", d.postID)
+	if walk.MsgBox(d.dialog, "Image Chest Post", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return
+	}
+	d.run("Deleting post...", func(ctx context.Context) (string, error) {
+		return "✓ Post deleted", deleteImgchestPost(ctx, d.postID)
+	}, func() {
+		d.model.items = nil
+		d.model.PublishRowsReset()
+		for _, b := range d.buttons() {
+			b.SetEnabled(false)
+		}
+		if imgchestPostID(d.app.postIDEdit.Text()) == d.postID {
+			d.app.postIDEdit.SetText("")
+		}
+	})
+}
+
+func (d *postDialog) current() (ImgchestImage, bool) {
+	i := d.table.CurrentIndex()
+	if i < 0 || i >= len(d.model.items) {
+		return ImgchestImage{}, false
+	}
+	return d.model.items[i], true
+}
+
+func (d *postDialog) onEditDescription() {
+	img, ok := d.current()
+	if !ok {
+		return
+	}
+	desc, ok := promptText(d.dialog, "Description for image "+img.ID, img.Description)
+	if !ok {
+		return
+	}
+	d.run("Updating description...", func(ctx context.Context) (string, error) {
+		return "", updateImgchestFile(ctx, img.ID, desc)
+	}, func() { d.reload("✓ Description updated") })
+}
+
+func (d *postDialog) onDeleteImage() {
+	img, ok := d.current()
+	if !ok {
+		return
+	}
+	message := fmt.Sprintf("Delete image %s from this post? This cannot be undone.", img.ID)
+	if walk.MsgBox(d.dialog, "Image Chest Post", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return
+	}
+	d.run("Deleting image...", func(ctx context.Context) (string, error) {
+		return "", deleteImgchestFile(ctx, img.ID)
+	}, func() { d.reload("✓ Image deleted") })
+}
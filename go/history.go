@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HistoryEntry struct {
+	Provider   string            `json:"provider"`
+	Source     string            `json:"source,omitempty"`
+	SHA256     string            `json:"sha256,omitempty"`
+	URL        string            `json:"url"`
+	ItemID     string            `json:"item_id,omitempty"`
+	GroupURL   string            `json:"group_url,omitempty"`
+	GroupID    string            `json:"group_id,omitempty"`
+	GroupToken string            `json:"group_token,omitempty"`
+	DeleteURL  string            `json:"delete_url,omitempty"`
+	Thumb      string            `json:"thumb,omitempty"`
+	UploadedAt time.Time         `json:"uploaded_at"`
+	ExpiresAt  *time.Time        `json:"expires_at,omitempty"`
+	DeletedAt  *time.Time        `json:"deleted_at,omitempty"`
+	Options    map[string]string `json:"options,omitempty"`
+}
+
+func (e HistoryEntry) Name() string {
+	if e.Source == "" {
+		return lastPathSegment(e.URL)
+	}
+	if strings.HasPrefix(e.Source, "http://") || strings.HasPrefix(e.Source, "https://") {
+		return e.Source
+	}
+	return filepath.Base(e.Source)
+}
+
+func (e HistoryEntry) Status() string {
+	if e.DeletedAt != nil {
+		return "deleted"
+	}
+	if e.ExpiresAt != nil {
+		if !timeNow().Before(*e.ExpiresAt) {
+			return "expired"
+		}
+		return "expires " + e.ExpiresAt.Local().Format("2006-01-02 15:04")
+	}
+	return ""
+}
+
+type HistoryQuery struct {
+	Text     string
+	Provider string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+func (q HistoryQuery) matches(e HistoryEntry) bool {
+	if q.Provider != "" && !strings.EqualFold(e.Provider, q.Provider) {
+		return false
+	}
+	if !q.Since.IsZero() && e.UploadedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.UploadedAt.Before(q.Until) {
+		return false
+	}
+	if text := strings.ToLower(strings.TrimSpace(q.Text)); text != "" {
+		haystack := strings.ToLower(e.Source + "\n" + e.URL + "\n" + e.GroupURL + "\n" + e.Options["title"])
+		if !strings.Contains(haystack, text) {
+			return false
+		}
+	}
+	return true
+}
+
+type HistoryStore struct {
+	path string
+}
+
+func NewHistoryStore(path string) *HistoryStore {
+	return &HistoryStore{path: path}
+}
+
+func defaultHistoryPath() (string, error) {
+	if p := os.Getenv("IMAGE_UPLOADER_HISTORY"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "image-uploader", "history.jsonl"), nil
+}
+
+func OpenHistoryStore() (*HistoryStore, error) {
+	path, err := defaultHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewHistoryStore(path), nil
+}
+
+func (s *HistoryStore) Append(entries ...HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to encode history entry: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+func (s *HistoryStore) Load() ([]HistoryEntry, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var e HistoryEntry
+			if json.Unmarshal(line, &e) == nil {
+				entries = append(entries, e)
+			}
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, fmt.Errorf("failed to read history: %w", err)
+		}
+	}
+}
+
+func (s *HistoryStore) update(fn func(*HistoryEntry) bool) error {
+	entries, err := s.Load()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range entries {
+		if fn(&entries[i]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to encode history entry: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+func (s *HistoryStore) MarkDeleted(deleted []HistoryEntry, at time.Time) error {
+	keys := make(map[string]struct{}, len(deleted))
+	for _, e := range deleted {
+		keys[e.Provider+" "+e.URL] = struct{}{}
+	}
+	return s.update(func(e *HistoryEntry) bool {
+		if _, ok := keys[e.Provider+" "+e.URL]; !ok || e.DeletedAt != nil {
+			return false
+		}
+		e.DeletedAt = &at
+		return true
+	})
+}
+
+// FindByURL returns the most recent entry for each URL, deletion URL or item
+// ID, falling back to a bare entry when the URL was not uploaded by this tool.
+func (s *HistoryStore) FindByURL(provider string, urls []string) ([]HistoryEntry, error) {
+	entries, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]HistoryEntry, len(entries))
+	for _, e := range entries {
+		if e.Provider != provider {
+			continue
+		}
+		for _, key := range []string{e.ItemID, e.DeleteURL, e.URL} {
+			if key != "" {
+				latest[key] = e
+			}
+		}
+	}
+	found := make([]HistoryEntry, 0, len(urls))
+	for _, u := range urls {
+		if e, ok := latest[u]; ok {
+			found = append(found, e)
+		} else {
+			found = append(found, HistoryEntry{Provider: provider, URL: u})
+		}
+	}
+	return found, nil
+}
+
+// GroupToken returns the most recent token recorded for a group, such as the
+// upload token of an sxcu collection created by this tool.
+func (s *HistoryStore) GroupToken(provider, groupID string) (string, error) {
+	entries, err := s.Load()
+	if err != nil {
+		return "", err
+	}
+	var token string
+	for _, e := range entries {
+		if e.Provider == provider && e.GroupID == groupID && e.GroupToken != "" {
+			token = e.GroupToken
+		}
+	}
+	return token, nil
+}
+
+// Search returns the matching entries, newest first.
+func (s *HistoryStore) Search(q HistoryQuery) ([]HistoryEntry, error) {
+	entries, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]HistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		if q.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].UploadedAt.After(matched[j].UploadedAt)
+	})
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	return matched, nil
+}
+
+func fileSHA256(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func historyOptions(provider string, job *UploadJob) map[string]string {
+	opts := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			opts[key] = value
+		}
+	}
+	set("title", job.Title)
+	set("description", job.Desc)
+	switch provider {
+	case "catbox":
+		set("album", strconv.FormatBool(job.CreateAlbum))
+		set("album_short", job.AlbumShort)
+		set("litterbox", job.LitterboxExpiry)
+	case "sxcu":
+		set("collection", strconv.FormatBool(job.CreateCollection))
+		set("collection_id", sxcuCollectionID(job.SxcuCollectionID))
+		if subdomain, _ := getSxcuSubdomain(); subdomain != "" {
+			set("subdomain", subdomain)
+		}
+		set("self_destruct", strconv.FormatBool(job.SxcuSelfDestruct))
+		set("paste", strconv.FormatBool(job.SxcuPaste))
+		og, _ := job.SxcuOG.encode()
+		set("og_properties", og)
+		if job.CreateCollection {
+			set("private", strconv.FormatBool(job.SxcuCollection.Private))
+		}
+	case "imgchest":
+		set("privacy", job.Imgchest.Privacy)
+		set("nsfw", strconv.FormatBool(job.Imgchest.NSFW))
+		set("anonymous", strconv.FormatBool(job.Imgchest.Anonymous))
+		set("post_id", job.PostID)
+	case "kek":
+		set("mature", strconv.FormatBool(job.KekMature))
+		set("visibility", job.KekVisibility)
+	}
+	return opts
+}
+
+func historyEntries(provider string, job *UploadJob, summary UploadSummary, uploadedAt time.Time) []HistoryEntry {
+	opts := historyOptions(provider, job)
+	entries := make([]HistoryEntry, 0, len(summary.Items))
+	for _, item := range summary.Items {
+		e := HistoryEntry{
+			Provider:   provider,
+			Source:     item.Source,
+			URL:        item.URL,
+			ItemID:     item.ID,
+			DeleteURL:  item.DeleteURL,
+			Thumb:      item.Thumb,
+			UploadedAt: uploadedAt,
+			Options:    opts,
+		}
+		if item.Source != "" && !strings.Contains(item.Source, "://") {
+			if abs, err := filepath.Abs(item.Source); err == nil {
+				e.Source = abs
+			}
+			e.SHA256 = fileSHA256(item.Source)
+		}
+		if !item.ExpiresAt.IsZero() {
+			expiresAt := item.ExpiresAt
+			e.ExpiresAt = &expiresAt
+		}
+		if g := summary.Group; g != nil {
+			e.GroupURL = g.URL
+			e.GroupID = g.ID
+			e.GroupToken = g.Token
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func rememberedGroupToken(provider, groupID string) string {
+	store, err := OpenHistoryStore()
+	if err != nil {
+		return ""
+	}
+	token, _ := store.GroupToken(provider, groupID)
+	return token
+}
+
+func recordUploadHistory(provider string, job *UploadJob, summary UploadSummary) error {
+	if len(summary.Items) == 0 {
+		return nil
+	}
+	store, err := OpenHistoryStore()
+	if err != nil {
+		return err
+	}
+	return store.Append(historyEntries(provider, job, summary, timeNow())...)
+}
+
+func recordShortLinks(items []*UploadResult) error {
+	if len(items) == 0 {
+		return nil
+	}
+	store, err := OpenHistoryStore()
+	if err != nil {
+		return err
+	}
+	now := timeNow()
+	entries := make([]HistoryEntry, 0, len(items))
+	for _, item := range items {
+		entries = append(entries, HistoryEntry{
+			Provider:   "sxcu",
+			Source:     item.Source,
+			URL:        item.URL,
+			ItemID:     item.ID,
+			DeleteURL:  item.DeleteURL,
+			UploadedAt: now,
+			Options:    map[string]string{"type": "link"},
+		})
+	}
+	return store.Append(entries...)
+}
+
+func deleteHistoryEntries(ctx context.Context, store *HistoryStore, entries []HistoryEntry) ([]HistoryEntry, []string) {
+	var providers []string
+	byProvider := make(map[string][]HistoryEntry)
+	for _, e := range entries {
+		if _, ok := byProvider[e.Provider]; !ok {
+			providers = append(providers, e.Provider)
+		}
+		byProvider[e.Provider] = append(byProvider[e.Provider], e)
+	}
+
+	var deleted []HistoryEntry
+	var errors []string
+	for _, name := range providers {
+		group := byProvider[name]
+		p, ok := LookupProvider(name)
+		deleter, canDelete := p.(UploadDeleter)
+		if !ok || !canDelete {
+			errors = append(errors, fmt.Sprintf("%s: deleting uploads is %v", name, errUnsupported))
+			continue
+		}
+		done, err := deleter.DeleteUploads(ctx, group)
+		deleted = append(deleted, done...)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if len(deleted) > 0 {
+		if err := store.MarkDeleted(deleted, timeNow()); err != nil {
+			errors = append(errors, fmt.Sprintf("History: %v", err))
+		}
+	}
+	return deleted, errors
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryStoreSearch(t *testing.T) {
+	store := NewHistoryStore(filepath.Join(t.TempDir(), "nested", "history.jsonl"))
+
+	tuesday := time.Date(2026, 10, 13, 14, 2, 0, 0, time.Local)
+	err := store.Append(
+		HistoryEntry{Provider: "catbox", Source: `C:\pics\cat.png`, URL: "https://files.catbox.moe/abc.png", UploadedAt: tuesday},
+		HistoryEntry{Provider: "sxcu", Source: "/home/me/dog.jpg", URL: "https://sxcu.net/def", UploadedAt: tuesday.Add(time.Hour)},
+		HistoryEntry{Provider: "catbox", Source: "https://example.com/x.gif", URL: "https://files.catbox.moe/ghi.gif", UploadedAt: tuesday.AddDate(0, 0, 2)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls := func(q HistoryQuery) []string {
+		t.Helper()
+		entries, err := store.Search(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.URL)
+		}
+		return got
+	}
+
+	if got, want := urls(HistoryQuery{}), []string{"https://files.catbox.moe/ghi.gif", "https://sxcu.net/def", "https://files.catbox.moe/abc.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("all = %v, want %v", got, want)
+	}
+	if got, want := urls(HistoryQuery{Provider: "CATBOX", Limit: 1}), []string{"https://files.catbox.moe/ghi.gif"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("provider+limit = %v, want %v", got, want)
+	}
+	day := time.Date(2026, 10, 13, 0, 0, 0, 0, time.Local)
+	if got, want := urls(HistoryQuery{Since: day, Until: day.AddDate(0, 0, 1), Text: "CAT"}), []string{"https://files.catbox.moe/abc.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("date+text = %v, want %v", got, want)
+	}
+}
+
+func TestHistoryStoreLoadMissingFile(t *testing.T) {
+	entries, err := NewHistoryStore(filepath.Join(t.TempDir(), "history.jsonl")).Load()
+	if err != nil || entries != nil {
+		t.Fatalf("Load() = %v, %v; want nil, nil", entries, err)
+	}
+}
+
+func TestImgchestBatchItemsUsesTrailingImages(t *testing.T) {
+	items := imgchestBatchItems(
+		[]string{"c.png", "d.png"},
+		[]string{"https://cdn/a", "https://cdn/b", "https://cdn/c", "https://cdn/d"},
+		[]string{"a", "b", "c", "d"},
+	)
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	if items[0].Source != "c.png" || items[0].ID != "c" || items[1].Source != "d.png" || items[1].URL != "https://cdn/d" {
+		t.Fatalf("items = %+v %+v", *items[0], *items[1])
+	}
+}
+
+func TestHistoryStoreMarkDeleted(t *testing.T) {
+	store := NewHistoryStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	uploaded := time.Date(2026, 10, 13, 14, 2, 0, 0, time.UTC)
+	if err := store.Append(
+		HistoryEntry{Provider: "catbox", URL: "https://files.catbox.moe/abc.png", ItemID: "abc.png", UploadedAt: uploaded},
+		HistoryEntry{Provider: "catbox", URL: "https://files.catbox.moe/def.png", ItemID: "def.png", UploadedAt: uploaded},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.FindByURL("catbox", []string{"https://files.catbox.moe/abc.png", "https://files.catbox.moe/zzz.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].ItemID != "abc.png" || found[1].ItemID != "" || found[1].Provider != "catbox" {
+		t.Fatalf("FindByURL() = %+v", found)
+	}
+
+	deletedAt := uploaded.Add(time.Hour)
+	if err := store.MarkDeleted(found[:1], deletedAt); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Status() != "deleted" || !entries[0].DeletedAt.Equal(deletedAt) {
+		t.Errorf("first entry = %+v, want deleted at %v", entries[0], deletedAt)
+	}
+	if entries[1].Status() != "" {
+		t.Errorf("second entry status = %q, want empty", entries[1].Status())
+	}
+}
+
+func TestHistoryStoreFindByDeleteURLAndItemID(t *testing.T) {
+	store := NewHistoryStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	uploaded := time.Date(2026, 10, 13, 14, 2, 0, 0, time.UTC)
+	if err := store.Append(
+		HistoryEntry{Provider: "sxcu", URL: "https://sxcu.net/abc.png", ItemID: "abc", DeleteURL: "https://sxcu.net/api/files/delete/abc/tok", UploadedAt: uploaded},
+		HistoryEntry{Provider: "imgchest", URL: "https://cdn.imgchest.com/files/xyz.png", ItemID: "xyz", UploadedAt: uploaded},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.FindByURL("sxcu", []string{"https://sxcu.net/api/files/delete/abc/tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].URL != "https://sxcu.net/abc.png" {
+		t.Fatalf("FindByURL(delete URL) = %+v", found)
+	}
+	if err := store.MarkDeleted(found, uploaded.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err = store.FindByURL("imgchest", []string{"xyz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].URL != "https://cdn.imgchest.com/files/xyz.png" {
+		t.Fatalf("FindByURL(item ID) = %+v", found)
+	}
+
+	entries, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Status() != "deleted" {
+		t.Errorf("sxcu entry status = %q, want deleted", entries[0].Status())
+	}
+}
+
+func TestSxcuSelfDestructExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 13, 14, 2, 0, 0, time.Local)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	job := &UploadJob{SxcuSelfDestruct: true}
+	item := sxcuUploadResult(job, &SxcuResponse{ID: "abc", URL: "https://sxcu.net/abc.png", DelURL: "https://sxcu.net/api/files/delete/abc/tok"}, "a.png")
+	if !item.ExpiresAt.Equal(now.Add(24 * time.Hour)) {
+		t.Fatalf("ExpiresAt = %v, want 24h after upload", item.ExpiresAt)
+	}
+
+	summary := UploadSummary{Items: []*UploadResult{item}}
+	want := "https://sxcu.net/abc.png  delete: https://sxcu.net/api/files/delete/abc/tok  expires: 2026-10-14 14:02"
+	if got := summary.Details(); len(got) != 1 || got[0] != want {
+		t.Fatalf("Details() = %q, want %q", got, want)
+	}
+	entries := historyEntries("sxcu", job, summary, now)
+	if entries[0].Status() != "expires 2026-10-14 14:02" || entries[0].Options["self_destruct"] != "true" {
+		t.Fatalf("history entry = %+v", entries[0])
+	}
+}
+
+func TestHistoryStoreGroupToken(t *testing.T) {
+	store := NewHistoryStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err := store.Append(
+		HistoryEntry{Provider: "sxcu", URL: "https://sxcu.net/a.png", GroupID: "coll1", GroupToken: "old"},
+		HistoryEntry{Provider: "sxcu", URL: "https://sxcu.net/b.png", GroupID: "coll1", GroupToken: "new"},
+		HistoryEntry{Provider: "sxcu", URL: "https://sxcu.net/c.png", GroupID: "coll1"},
+		HistoryEntry{Provider: "catbox", URL: "https://files.catbox.moe/d.png", GroupID: "coll1", GroupToken: "other"},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.GroupToken("sxcu", "coll1"); err != nil || got != "new" {
+		t.Fatalf("GroupToken() = %q, %v; want \"new\"", got, err)
+	}
+	if got, _ := store.GroupToken("sxcu", "missing"); got != "" {
+		t.Fatalf("GroupToken(missing) = %q, want empty", got)
+	}
+	if got := sxcuCollectionID("https://sxcu.net/c/coll1/"); got != "coll1" {
+		t.Fatalf("sxcuCollectionID() = %q, want coll1", got)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLockExclusiveFailsWhileHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload.lock")
+
+	first, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	if ok, err := tryLockExclusive(first); !ok || err != nil {
+		t.Fatalf("first lock: got (%v, %v), want (true, nil)", ok, err)
+	}
+	if ok, err := tryLockExclusive(second); ok || err != nil {
+		t.Fatalf("second lock while held: got (%v, %v), want (false, nil)", ok, err)
+	}
+
+	if err := unlockExclusive(first); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := tryLockExclusive(second); !ok || err != nil {
+		t.Fatalf("second lock after release: got (%v, %v), want (true, nil)", ok, err)
+	}
+	unlockExclusive(second)
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -13,98 +15,11 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
-	"unsafe"
-
-	"golang.org/x/sys/windows"
-)
-
-var (
-	user32                        = syscall.NewLazyDLL("user32.dll")
-	messageBoxW                   = user32.NewProc("MessageBoxW")
-	setProcessDPIAware            = user32.NewProc("SetProcessDPIAware")
-	setProcessDpiAwarenessContext = user32.NewProc("SetProcessDpiAwarenessContext")
-	comdlg32                      = syscall.NewLazyDLL("comdlg32.dll")
-	getOpenFileNameW              = comdlg32.NewProc("GetOpenFileNameW")
-	dwmapi                        = syscall.NewLazyDLL("dwmapi.dll")
-	dwmSetWindowAttr              = dwmapi.NewProc("DwmSetWindowAttribute")
-	advapi32                      = syscall.NewLazyDLL("advapi32.dll")
-	regOpenKeyExW                 = advapi32.NewProc("RegOpenKeyExW")
-	regQueryValueExW              = advapi32.NewProc("RegQueryValueExW")
-	regCloseKey                   = advapi32.NewProc("RegCloseKey")
-)
-
-const (
-	DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2 = ^uintptr(4) // -5
-)
-
-func init() {
-	if setProcessDpiAwarenessContext.Find() == nil {
-		setProcessDpiAwarenessContext.Call(DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2)
-	} else if setProcessDPIAware.Find() == nil {
-		setProcessDPIAware.Call()
-	}
-}
-
-const (
-	MB_OK              = 0x00000000
-	MB_ICONERROR       = 0x00000010
-	MB_ICONINFORMATION = 0x00000040
-
-	DWMWA_USE_IMMERSIVE_DARK_MODE = 20
-
-	HKEY_CURRENT_USER = 0x80000001
-	KEY_READ          = 0x20019
-)
-
-var (
-	titleInfo, _  = syscall.UTF16PtrFromString("Image Uploader")
-	titleError, _ = syscall.UTF16PtrFromString("Image Uploader - Error")
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
-func showError(message string) {
-	msg, _ := syscall.UTF16PtrFromString(message)
-	messageBoxW.Call(0, uintptr(unsafe.Pointer(msg)), uintptr(unsafe.Pointer(titleError)), MB_OK|MB_ICONERROR)
-}
-
-func showInfo(message string) {
-	msg, _ := syscall.UTF16PtrFromString(message)
-	messageBoxW.Call(0, uintptr(unsafe.Pointer(msg)), uintptr(unsafe.Pointer(titleInfo)), MB_OK|MB_ICONINFORMATION)
-}
-
-func IsSystemDarkMode() bool {
-	subKey, _ := syscall.UTF16PtrFromString(`Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`)
-	valueName, _ := syscall.UTF16PtrFromString("AppsUseLightTheme")
-
-	var hKey uintptr
-	ret, _, _ := regOpenKeyExW.Call(HKEY_CURRENT_USER, uintptr(unsafe.Pointer(subKey)), 0, KEY_READ, uintptr(unsafe.Pointer(&hKey)))
-	if ret != 0 {
-		return false
-	}
-	defer regCloseKey.Call(hKey)
-
-	var dataType uint32
-	var data uint32
-	dataSize := uint32(4)
-	ret, _, _ = regQueryValueExW.Call(hKey, uintptr(unsafe.Pointer(valueName)), 0, uintptr(unsafe.Pointer(&dataType)), uintptr(unsafe.Pointer(&data)), uintptr(unsafe.Pointer(&dataSize)))
-	if ret != 0 {
-		return false
-	}
-	return data == 0
-}
-
-func SetDarkModeTitleBar(hwnd uintptr, dark bool) {
-	if dwmSetWindowAttr.Find() != nil {
-		return
-	}
-	var value int32
-	if dark {
-		value = 1
-	}
-	dwmSetWindowAttr.Call(hwnd, DWMWA_USE_IMMERSIVE_DARK_MODE, uintptr(unsafe.Pointer(&value)), 4)
-}
-
 type RateLimitEntry struct {
 	Limit       int   `json:"limit"`
 	Remaining   int   `json:"remaining"`
@@ -135,24 +50,16 @@ const (
 )
 
 const (
-	sxcuFileUploadBucket = "__sxcu_file_upload__"
-	sxcuCollectionBucket = "__sxcu_collection__"
-	sxcuGlobalBucket     = "__sxcu_global__"
-)
-
-var (
-	rateLimits     AllRateLimits
-	rateLimitMutex sync.Mutex
+	sxcuFileUploadBucket  = "__sxcu_file_upload__"
+	sxcuCollectionBucket  = "__sxcu_collection__"
+	sxcuFileDeleteBucket  = "__sxcu_file_delete__"
+	sxcuLinkBucket        = "__sxcu_link__"
+	sxcuLinkDeleteBucket  = "__sxcu_link_delete__"
+	sxcuPasteBucket       = "__sxcu_paste__"
+	sxcuPasteDeleteBucket = "__sxcu_paste_delete__"
+	sxcuGlobalBucket      = "__sxcu_global__"
 )
 
-func init() {
-	rateLimits = AllRateLimits{
-		Sxcu:     SxcuRateLimitState{Buckets: make(map[string]*RateLimitEntry)},
-		Imgchest: ImgchestRateLimitState{},
-	}
-	loadRateLimitsFromFile()
-}
-
 func getRateLimitFilePath() string {
 	return filepath.Join(os.TempDir(), "image_uploader_rate_limits.json")
 }
@@ -165,7 +72,6 @@ func getUploadLockPath() string {
 	return filepath.Join(os.TempDir(), "image_uploader_upload.lock")
 }
 
-var lockFile *os.File
 var uploadLockFile *os.File
 
 func TryAcquireUploadLock() (bool, error) {
@@ -175,110 +81,39 @@ func TryAcquireUploadLock() (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	handle := windows.Handle(uploadLockFile.Fd())
-	var overlapped windows.Overlapped
-	err = windows.LockFileEx(handle, windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
-	if err != nil {
+	acquired, err := tryLockExclusive(uploadLockFile)
+	if err != nil || !acquired {
 		uploadLockFile.Close()
 		uploadLockFile = nil
-		if err == windows.ERROR_LOCK_VIOLATION {
-			return false, nil
-		}
 		return false, err
 	}
 	return true, nil
 }
 
-func AcquireUploadLock() error {
-	lockPath := getUploadLockPath()
-	var err error
-	uploadLockFile, err = os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0666)
-	if err != nil {
-		return err
-	}
-	handle := windows.Handle(uploadLockFile.Fd())
-	var overlapped windows.Overlapped
-	err = windows.LockFileEx(handle, windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &overlapped)
-	if err != nil {
-		uploadLockFile.Close()
-		uploadLockFile = nil
-		return err
+func AcquireUploadLock(ctx context.Context) error {
+	for {
+		acquired, err := TryAcquireUploadLock()
+		if err != nil || acquired {
+			return err
+		}
+		if err := sleepContext(ctx, 250*time.Millisecond); err != nil {
+			return err
+		}
 	}
-	return nil
 }
 
 func ReleaseUploadLock() {
 	if uploadLockFile != nil {
-		handle := windows.Handle(uploadLockFile.Fd())
-		var overlapped windows.Overlapped
-		windows.UnlockFileEx(handle, 0, 1, 0, &overlapped)
+		unlockExclusive(uploadLockFile)
 		uploadLockFile.Close()
 		uploadLockFile = nil
 	}
 }
 
-func acquireFileLock() error {
-	lockPath := getRateLimitLockPath()
-	var err error
-	lockFile, err = os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0666)
-	if err != nil {
-		return err
-	}
-	handle := windows.Handle(lockFile.Fd())
-	var overlapped windows.Overlapped
-	err = windows.LockFileEx(handle, windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &overlapped)
-	if err != nil {
-		lockFile.Close()
-		lockFile = nil
-		return err
-	}
-	return nil
-}
-
-func releaseFileLock() {
-	if lockFile != nil {
-		handle := windows.Handle(lockFile.Fd())
-		var overlapped windows.Overlapped
-		windows.UnlockFileEx(handle, 0, 1, 0, &overlapped)
-		lockFile.Close()
-		lockFile = nil
-	}
-}
-
-func loadRateLimitsFromFile() {
-	path := getRateLimitFilePath()
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return
-	}
-	var loaded AllRateLimits
-	if err := json.Unmarshal(data, &loaded); err != nil {
-		return
-	}
-	if loaded.Sxcu.Buckets == nil {
-		loaded.Sxcu.Buckets = make(map[string]*RateLimitEntry)
-	}
-	rateLimits = loaded
-	cleanupExpiredEntries()
-}
-
-func saveRateLimitsToFile() {
-	path := getRateLimitFilePath()
-	data, err := json.Marshal(rateLimits)
-	if err != nil {
-		return
-	}
-	os.WriteFile(path, data, 0644)
-}
-
-func withFileLock(fn func()) {
-	if err := acquireFileLock(); err == nil {
-		loadRateLimitsFromFile()
-		fn()
-		saveRateLimitsToFile()
-		releaseFileLock()
-	} else {
-		fn()
+func newRateLimitState() AllRateLimits {
+	return AllRateLimits{
+		Sxcu:     SxcuRateLimitState{Buckets: make(map[string]*RateLimitEntry)},
+		Imgchest: ImgchestRateLimitState{},
 	}
 }
 
@@ -286,20 +121,18 @@ func isRateLimitExpired(entry *RateLimitEntry, nowMs int64) bool {
 	return entry == nil || nowMs >= entry.ResetAt
 }
 
-func cleanupExpiredEntries() {
-	nowMs := time.Now().UnixMilli()
-
-	if rateLimits.Imgchest.Default != nil && isRateLimitExpired(rateLimits.Imgchest.Default, nowMs) {
-		rateLimits.Imgchest.Default = nil
+func (s *AllRateLimits) cleanupExpiredEntries(nowMs int64) {
+	if s.Imgchest.Default != nil && isRateLimitExpired(s.Imgchest.Default, nowMs) {
+		s.Imgchest.Default = nil
 	}
 
-	if rateLimits.Sxcu.Global != nil && isRateLimitExpired(rateLimits.Sxcu.Global, nowMs) {
-		rateLimits.Sxcu.Global = nil
+	if s.Sxcu.Global != nil && isRateLimitExpired(s.Sxcu.Global, nowMs) {
+		s.Sxcu.Global = nil
 	}
 
-	for bucket := range rateLimits.Sxcu.Buckets {
-		if isRateLimitExpired(rateLimits.Sxcu.Buckets[bucket], nowMs) {
-			delete(rateLimits.Sxcu.Buckets, bucket)
+	for bucket := range s.Sxcu.Buckets {
+		if isRateLimitExpired(s.Sxcu.Buckets[bucket], nowMs) {
+			delete(s.Sxcu.Buckets, bucket)
 		}
 	}
 }
@@ -375,12 +208,10 @@ type RateLimitCheckResult struct {
 	ResetAt int64
 }
 
-func checkSxcuRateLimitInternal(routeBucket string, nowMs int64) RateLimitCheckResult {
-	cleanupExpiredEntries()
-
-	if rateLimits.Sxcu.Global != nil && !isRateLimitExpired(rateLimits.Sxcu.Global, nowMs) {
-		if rateLimits.Sxcu.Global.Remaining < 1 {
-			waitMs := rateLimits.Sxcu.Global.ResetAt - nowMs + 100
+func (s *AllRateLimits) checkSxcu(routeBucket string, nowMs int64) RateLimitCheckResult {
+	if s.Sxcu.Global != nil && !isRateLimitExpired(s.Sxcu.Global, nowMs) {
+		if s.Sxcu.Global.Remaining < 1 {
+			waitMs := s.Sxcu.Global.ResetAt - nowMs + 100
 			if waitMs < 100 {
 				waitMs = 100
 			}
@@ -389,13 +220,13 @@ func checkSxcuRateLimitInternal(routeBucket string, nowMs int64) RateLimitCheckR
 				WaitMs:  waitMs,
 				Reason:  "global",
 				Bucket:  sxcuGlobalBucket,
-				ResetAt: rateLimits.Sxcu.Global.ResetAt,
+				ResetAt: s.Sxcu.Global.ResetAt,
 			}
 		}
 	}
 
 	if routeBucket != "" {
-		if entry, ok := rateLimits.Sxcu.Buckets[routeBucket]; ok && !isRateLimitExpired(entry, nowMs) {
+		if entry, ok := s.Sxcu.Buckets[routeBucket]; ok && !isRateLimitExpired(entry, nowMs) {
 			if entry.Remaining < 1 {
 				waitMs := entry.ResetAt - nowMs + 100
 				if waitMs < 100 {
@@ -415,21 +246,13 @@ func checkSxcuRateLimitInternal(routeBucket string, nowMs int64) RateLimitCheckR
 	return RateLimitCheckResult{Allowed: true, WaitMs: 0}
 }
 
-func checkSxcuRateLimit(routeBucket string) RateLimitCheckResult {
-	rateLimitMutex.Lock()
-	defer rateLimitMutex.Unlock()
-
-	var result RateLimitCheckResult
-	withFileLock(func() {
-		nowMs := time.Now().UnixMilli()
-		result = checkSxcuRateLimitInternal(routeBucket, nowMs)
-	})
-	return result
-}
+func (s *AllRateLimits) updateSxcu(routeBucket string, headers RateLimitHeaders, isGlobalError bool, isRateLimitError bool, nowMs int64) {
+	if s.Sxcu.Buckets == nil {
+		s.Sxcu.Buckets = make(map[string]*RateLimitEntry)
+	}
 
-func updateSxcuRateLimitInternal(routeBucket string, headers RateLimitHeaders, isGlobalError bool, isRateLimitError bool, nowMs int64) {
 	if isGlobalError || headers.IsGlobal {
-		rateLimits.Sxcu.Global = &RateLimitEntry{
+		s.Sxcu.Global = &RateLimitEntry{
 			Limit:       sxcuGlobalRequestsPerMinute,
 			Remaining:   0,
 			ResetAt:     createRateLimitEntry(headers, nowMs).ResetAt,
@@ -437,14 +260,14 @@ func updateSxcuRateLimitInternal(routeBucket string, headers RateLimitHeaders, i
 			LastUpdated: nowMs,
 		}
 	} else {
-		if rateLimits.Sxcu.Global != nil && !isRateLimitExpired(rateLimits.Sxcu.Global, nowMs) {
-			rateLimits.Sxcu.Global.Remaining--
-			if rateLimits.Sxcu.Global.Remaining < 0 {
-				rateLimits.Sxcu.Global.Remaining = 0
+		if s.Sxcu.Global != nil && !isRateLimitExpired(s.Sxcu.Global, nowMs) {
+			s.Sxcu.Global.Remaining--
+			if s.Sxcu.Global.Remaining < 0 {
+				s.Sxcu.Global.Remaining = 0
 			}
-			rateLimits.Sxcu.Global.LastUpdated = nowMs
+			s.Sxcu.Global.LastUpdated = nowMs
 		} else {
-			rateLimits.Sxcu.Global = &RateLimitEntry{
+			s.Sxcu.Global = &RateLimitEntry{
 				Limit:       sxcuGlobalRequestsPerMinute,
 				Remaining:   sxcuGlobalRequestsPerMinute - 1,
 				ResetAt:     nowMs + sxcuGlobalWindowMs,
@@ -455,14 +278,14 @@ func updateSxcuRateLimitInternal(routeBucket string, headers RateLimitHeaders, i
 	}
 
 	if headers.Bucket != "" && headers.Limit >= 0 && headers.Remaining >= 0 {
-		rateLimits.Sxcu.Buckets[headers.Bucket] = createRateLimitEntry(headers, nowMs)
+		s.Sxcu.Buckets[headers.Bucket] = createRateLimitEntry(headers, nowMs)
 	}
 
 	if routeBucket != "" {
-		entry, ok := rateLimits.Sxcu.Buckets[routeBucket]
+		entry, ok := s.Sxcu.Buckets[routeBucket]
 		if !ok || isRateLimitExpired(entry, nowMs) {
 			if headers.Limit > 0 && headers.Remaining >= 0 {
-				rateLimits.Sxcu.Buckets[routeBucket] = createRateLimitEntry(headers, nowMs)
+				s.Sxcu.Buckets[routeBucket] = createRateLimitEntry(headers, nowMs)
 			}
 		} else if !isRateLimitError {
 			entry.Remaining--
@@ -482,20 +305,8 @@ func updateSxcuRateLimitInternal(routeBucket string, headers RateLimitHeaders, i
 	}
 }
 
-func updateSxcuRateLimit(routeBucket string, headers RateLimitHeaders, isGlobalError bool, isRateLimitError bool) {
-	rateLimitMutex.Lock()
-	defer rateLimitMutex.Unlock()
-
-	withFileLock(func() {
-		nowMs := time.Now().UnixMilli()
-		updateSxcuRateLimitInternal(routeBucket, headers, isGlobalError, isRateLimitError, nowMs)
-	})
-}
-
-func checkImgchestRateLimitInternal(nowMs int64) RateLimitCheckResult {
-	cleanupExpiredEntries()
-
-	entry := rateLimits.Imgchest.Default
+func (s *AllRateLimits) checkImgchest(nowMs int64) RateLimitCheckResult {
+	entry := s.Imgchest.Default
 	if entry == nil || isRateLimitExpired(entry, nowMs) {
 		return RateLimitCheckResult{Allowed: true, WaitMs: 0}
 	}
@@ -516,44 +327,33 @@ func checkImgchestRateLimitInternal(nowMs int64) RateLimitCheckResult {
 	return RateLimitCheckResult{Allowed: true, WaitMs: 0}
 }
 
-func checkImgchestRateLimit() RateLimitCheckResult {
-	rateLimitMutex.Lock()
-	defer rateLimitMutex.Unlock()
-
-	var result RateLimitCheckResult
-	withFileLock(func() {
-		nowMs := time.Now().UnixMilli()
-		result = checkImgchestRateLimitInternal(nowMs)
-	})
-	return result
-}
-
-func updateImgchestRateLimitInternal(headers RateLimitHeaders, nowMs int64) {
+func (s *AllRateLimits) updateImgchest(headers RateLimitHeaders, nowMs int64) {
 	if headers.Limit >= 0 && headers.Remaining >= 0 {
-		rateLimits.Imgchest.Default = &RateLimitEntry{
+		s.Imgchest.Default = &RateLimitEntry{
 			Limit:       headers.Limit,
 			Remaining:   headers.Remaining,
 			ResetAt:     nowMs + imgchestWindowMs,
 			WindowStart: nowMs,
 			LastUpdated: nowMs,
 		}
-	} else if rateLimits.Imgchest.Default != nil {
-		rateLimits.Imgchest.Default.Remaining--
-		if rateLimits.Imgchest.Default.Remaining < 0 {
-			rateLimits.Imgchest.Default.Remaining = 0
+	} else if s.Imgchest.Default != nil {
+		s.Imgchest.Default.Remaining--
+		if s.Imgchest.Default.Remaining < 0 {
+			s.Imgchest.Default.Remaining = 0
 		}
-		rateLimits.Imgchest.Default.LastUpdated = nowMs
+		s.Imgchest.Default.LastUpdated = nowMs
 	}
 }
 
-func updateImgchestRateLimit(headers RateLimitHeaders) {
-	rateLimitMutex.Lock()
-	defer rateLimitMutex.Unlock()
-
-	withFileLock(func() {
-		nowMs := time.Now().UnixMilli()
-		updateImgchestRateLimitInternal(headers, nowMs)
-	})
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 func calculateExponentialBackoff(attempt int, baseDelayMs, maxDelayMs int64) time.Duration {
@@ -703,7 +503,47 @@ func decodeKekPostResponse(body []byte) (*KekPostResponse, error) {
 	return &result, nil
 }
 
-func uploadFileToKek(filePath, apiKey string) (*KekPostResponse, error) {
+func uploadFileToKek(ctx context.Context, filePath, apiKey string) (*KekPostResponse, error) {
+	var size int64
+	if info, err := os.Stat(filePath); err == nil {
+		size = info.Size()
+	}
+	started := timeNow()
+
+	var result *KekPostResponse
+	err := withUploadRetry(ctx, kekMaxRetries, func() error {
+		var err error
+		result, err = uploadFileToKekOnce(ctx, filePath, apiKey)
+		return err
+	}, func() bool {
+		result = findKekUpload(ctx, apiKey, size, started)
+		return result != nil
+	})
+	return result, err
+}
+
+// findKekUpload looks among the newest posts for one of the given size made
+// since the upload started, which kek created before its reply was lost.
+func findKekUpload(ctx context.Context, apiKey string, size int64, since time.Time) *KekPostResponse {
+	posts, err := listKekPosts(ctx, apiKey, "")
+	if err != nil || size <= 0 {
+		return nil
+	}
+	for _, p := range posts {
+		created, err := time.Parse(time.RFC3339, p.CreatedAt)
+		if err == nil && p.Size == size && !created.Before(since.Add(-time.Minute)) {
+			return &p.KekPostResponse
+		}
+	}
+	return nil
+}
+
+func uploadFileToKekOnce(ctx context.Context, filePath, apiKey string) (*KekPostResponse, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
 	pr, pw := io.Pipe()
 	writer := multipart.NewWriter(pw)
 	contentType := writer.FormDataContentType()
@@ -712,13 +552,6 @@ func uploadFileToKek(filePath, apiKey string) (*KekPostResponse, error) {
 	go func() {
 		defer pw.Close()
 		defer writer.Close()
-
-		file, err := os.Open(filePath)
-		if err != nil {
-			pw.CloseWithError(err)
-			errCh <- err
-			return
-		}
 		defer file.Close()
 
 		part, err := writer.CreateFormFile("file", filepath.Base(filePath))
@@ -729,7 +562,7 @@ func uploadFileToKek(filePath, apiKey string) (*KekPostResponse, error) {
 		}
 
 		bufp := copyBufPool.Get().(*[]byte)
-		_, err = io.CopyBuffer(part, file, *bufp)
+		_, err = copyFileWithProgress(ctx, part, file, filePath, *bufp)
 		copyBufPool.Put(bufp)
 		if err != nil {
 			pw.CloseWithError(err)
@@ -739,7 +572,7 @@ func uploadFileToKek(filePath, apiKey string) (*KekPostResponse, error) {
 		errCh <- nil
 	}()
 
-	req, err := http.NewRequest("POST", kekAPIBaseURL+"/posts", pr)
+	req, err := http.NewRequestWithContext(ctx, "POST", kekAPIBaseURL+"/posts", pr)
 	if err != nil {
 		pr.Close()
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -747,9 +580,9 @@ func uploadFileToKek(filePath, apiKey string) (*KekPostResponse, error) {
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("x-kek-auth", apiKey)
 
-	resp, err := httpClient.Do(req)
+	resp, err := sendOnce(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -757,213 +590,553 @@ func uploadFileToKek(filePath, apiKey string) (*KekPostResponse, error) {
 		return nil, fmt.Errorf("failed to write multipart: %w", pipeErr)
 	}
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	body, err := readKekResponse(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, string(body))
+		return nil, kekUploadError(err)
 	}
-
 	return decodeKekPostResponse(body)
 }
 
-func uploadURLToKek(targetURL, apiKey string) (*KekPostResponse, error) {
+// kekUploadError classifies a failed upload reply: a cut-off reply may
+// follow a post kek created, just like a 5xx.
+func kekUploadError(err error) error {
+	var apiErr *KekAPIError
+	if !errors.As(err, &apiErr) {
+		return maybeDelivered(err)
+	}
+	return uploadStatusError(apiErr.Status, err)
+}
+
+func uploadURLToKek(ctx context.Context, targetURL, apiKey string) (*KekPostResponse, error) {
 	parsed, err := neturl.ParseRequestURI(targetURL)
 	if err != nil || parsed == nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
 		return nil, fmt.Errorf("invalid URL")
 	}
 
+	// Nothing ties a post to the URL it came from, so a post kek may have
+	// made before failing cannot be found and the upload is not repeated.
+	var result *KekPostResponse
+	err = withRetry(ctx, kekMaxRetries, func() error {
+		result, err = uploadURLToKekOnce(ctx, targetURL, apiKey)
+		return err
+	})
+	return result, err
+}
+
+func uploadURLToKekOnce(ctx context.Context, targetURL, apiKey string) (*KekPostResponse, error) {
+
 	form := neturl.Values{}
 	form.Set("url", targetURL)
-	req, err := http.NewRequest("POST", kekAPIBaseURL+"/posts", strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", kekAPIBaseURL+"/posts", strings.NewReader(form.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("x-kek-auth", apiKey)
 
-	resp, err := httpClient.Do(req)
+	resp, err := sendOnce(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	body, err := readKekResponse(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, kekUploadError(err)
 	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, string(body))
-	}
-
 	return decodeKekPostResponse(body)
 }
 
-func setKekPostMature(postID, apiKey string, mature bool) error {
+func setKekPostMature(ctx context.Context, postID, apiKey string, mature bool) error {
+	return setKekPostFlag(ctx, postID, apiKey, "mature", mature)
+}
+
+// setKekPostPublic changes who can see the post. Only some kek plans allow
+// it; a refusal is reported as a plan error rather than the raw response.
+func setKekPostPublic(ctx context.Context, postID, apiKey string, public bool) error {
+	err := setKekPostFlag(ctx, postID, apiKey, "public", public)
+	var apiErr *KekAPIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch {
+	case apiErr.Status == http.StatusUnauthorized:
+		return fmt.Errorf("kek rejected the API key (%s)", apiErr.Message())
+	case apiErr.IsPlanRefusal():
+		visibility := "private"
+		if public {
+			visibility = "public"
+		}
+		return fmt.Errorf("your kek plan does not allow making posts %s (%s); see https://kek.sh/pricing", visibility, apiErr.Message())
+	}
+	return err
+}
+
+func setKekPostFlag(ctx context.Context, postID, apiKey, flag string, value bool) error {
 	if strings.TrimSpace(postID) == "" {
 		return fmt.Errorf("post ID is required")
 	}
-	payload, err := json.Marshal(map[string]bool{"value": mature})
+	payload, err := json.Marshal(map[string]bool{"value": value})
 	if err != nil {
-		return fmt.Errorf("failed to marshal maturity payload: %w", err)
+		return fmt.Errorf("failed to marshal %s payload: %w", flag, err)
 	}
+	_, err = kekRequest(ctx, "PUT", "/posts/"+neturl.PathEscape(postID)+"/"+flag, payload, apiKey)
+	return err
+}
+
+// kekRequest sends an authenticated request to the kek API and returns the
+// response body.
+func kekRequest(ctx context.Context, method, path string, payload []byte, apiKey string) ([]byte, error) {
+	var result []byte
+	err := withRetry(ctx, kekMaxRetries, func() error {
+		var err error
+		result, err = kekRequestOnce(ctx, method, path, payload, apiKey)
+		return err
+	})
+	return result, err
+}
 
-	req, err := http.NewRequest("PUT", kekAPIBaseURL+"/posts/"+neturl.PathEscape(postID)+"/mature", bytes.NewReader(payload))
+func kekRequestOnce(ctx context.Context, method, path string, payload []byte, apiKey string) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, kekAPIBaseURL+path, body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
-	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-kek-auth", apiKey)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, retryable(fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 8192))
+	// kekRequest only sends requests that are safe to repeat, so a failed
+	// read or a 5xx or 429 status is retried.
+	result, err := readKekResponse(resp)
+	var apiErr *KekAPIError
+	if err != nil && (!errors.As(err, &apiErr) || isRetryableStatus(apiErr.Status)) {
+		return nil, retryable(err)
+	}
+	return result, err
+}
+
+// readKekResponse returns the body of a successful response. Other statuses
+// are reported as KekAPIError.
+func readKekResponse(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
 	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, string(body))
+		return nil, &KekAPIError{Status: resp.StatusCode, Body: string(body)}
 	}
-	return nil
+	return body, nil
 }
 
-func uploadFileToCatbox(filePath string) (string, error) {
-	pr, pw := io.Pipe()
-	writer := multipart.NewWriter(pw)
-	contentType := writer.FormDataContentType()
+type KekAPIError struct {
+	Status int
+	Body   string
+}
 
-	errCh := make(chan error, 1)
-	go func() {
-		defer pw.Close()
-		defer writer.Close()
+func (e *KekAPIError) Error() string {
+	return fmt.Sprintf("API error: status=%d body=%s", e.Status, e.Body)
+}
 
-		if err := writer.WriteField("reqtype", "fileupload"); err != nil {
-			pw.CloseWithError(err)
-			errCh <- err
-			return
+// Message returns the error or message field of a JSON body, or the body.
+func (e *KekAPIError) Message() string {
+	var body struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(e.Body), &body) == nil {
+		if msg := firstNonEmpty(body.Error, body.Message); msg != "" {
+			return msg
 		}
+	}
+	if msg := strings.TrimSpace(e.Body); msg != "" {
+		return msg
+	}
+	return http.StatusText(e.Status)
+}
 
-		file, err := os.Open(filePath)
-		if err != nil {
-			pw.CloseWithError(err)
-			errCh <- err
-			return
-		}
-		defer file.Close()
+// IsPlanRefusal reports whether kek refused the request because the
+// account's plan does not include it. The API docs only say that changing a
+// post's visibility depends on the plan, so only a 402 or a 403 that names
+// the plan counts; other refusals, such as a bad API key, are left as they are.
+func (e *KekAPIError) IsPlanRefusal() bool {
+	switch e.Status {
+	case http.StatusPaymentRequired:
+		return true
+	case http.StatusForbidden:
+		return strings.Contains(strings.ToLower(e.Message()), "plan")
+	}
+	return false
+}
 
-		part, err := writer.CreateFormFile("fileToUpload", filepath.Base(filePath))
-		if err != nil {
-			pw.CloseWithError(err)
-			errCh <- err
-			return
-		}
+// kekPostsPageSize is the most posts GET /posts returns at once.
+const kekPostsPageSize = 48
 
-		bufp := copyBufPool.Get().(*[]byte)
-		_, err = io.CopyBuffer(part, file, *bufp)
-		copyBufPool.Put(bufp)
-		if err != nil {
-			pw.CloseWithError(err)
-			errCh <- err
-			return
-		}
-		errCh <- nil
-	}()
+type KekPost struct {
+	KekPostResponse
+	Public    bool   `json:"public"`
+	Mature    bool   `json:"mature"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"createdAt"`
+}
 
-	req, err := http.NewRequest("POST", "https://catbox.moe/user/api.php", pr)
-	if err != nil {
-		pr.Close()
-		return "", fmt.Errorf("failed to create request: %w", err)
+// listKekPosts returns the newest posts, or those older than post from when
+// it is set. Pass the ID of the last returned post to get the next page.
+func listKekPosts(ctx context.Context, apiKey, from string) ([]KekPost, error) {
+	path := "/posts"
+	if from != "" {
+		path += "?from=" + neturl.QueryEscape(from)
 	}
-	req.Header.Set("Content-Type", contentType)
-
-	resp, err := httpClient.Do(req)
+	body, err := kekRequest(ctx, "GET", path, nil, apiKey)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("failed to list posts: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if pipeErr := <-errCh; pipeErr != nil {
-		return "", fmt.Errorf("failed to write multipart: %w", pipeErr)
+	var posts []KekPost
+	if err := json.Unmarshal(body, &posts); err != nil {
+		var wrapped struct {
+			Posts []KekPost `json:"posts"`
+			Data  []KekPost `json:"data"`
+		}
+		if json.Unmarshal(body, &wrapped) != nil {
+			return nil, fmt.Errorf("failed to parse posts: %s", strings.TrimSpace(string(body)))
+		}
+		posts = append(wrapped.Posts, wrapped.Data...)
 	}
+	return posts, nil
+}
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+func deleteKekPost(ctx context.Context, postID, apiKey string) error {
+	if strings.TrimSpace(postID) == "" {
+		return fmt.Errorf("post ID is required")
 	}
-
-	result := strings.TrimSpace(string(body))
-	if !strings.HasPrefix(result, "https://") {
-		return "", fmt.Errorf("upload failed: %s", result)
+	if _, err := kekRequest(ctx, "DELETE", "/posts/"+neturl.PathEscape(postID), nil, apiKey); err != nil {
+		return fmt.Errorf("post %s: %w", postID, err)
 	}
+	return nil
+}
 
-	return result, nil
+// clearKekStorage deletes every post on the account. It cannot be undone.
+func clearKekStorage(ctx context.Context, apiKey string) error {
+	if _, err := kekRequest(ctx, "DELETE", "/posts", nil, apiKey); err != nil {
+		return fmt.Errorf("failed to clear storage: %w", err)
+	}
+	return nil
 }
 
-func uploadURLToCatbox(targetURL string) (string, error) {
-	pr, pw := io.Pipe()
-	writer := multipart.NewWriter(pw)
-	contentType := writer.FormDataContentType()
+var customCatboxUserhash string
 
-	go func() {
-		defer pw.Close()
-		defer writer.Close()
-		writer.WriteField("reqtype", "urlupload")
-		writer.WriteField("url", targetURL)
-	}()
+func SetCatboxUserhash(userhash string) {
+	customCatboxUserhash = userhash
+}
 
-	req, err := http.NewRequest("POST", "https://catbox.moe/user/api.php", pr)
-	if err != nil {
-		pr.Close()
-		return "", fmt.Errorf("failed to create request: %w", err)
+func getCatboxUserhash() string {
+	if customCatboxUserhash != "" {
+		return customCatboxUserhash
 	}
-	req.Header.Set("Content-Type", contentType)
 
-	resp, err := httpClient.Do(req)
+	exePath, err := os.Executable()
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return ""
+	}
+	exeDir := filepath.Dir(exePath)
+	configFile := filepath.Join(exeDir, "..", "catbox.txt")
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return ""
+	}
+
+	data = bytes.TrimSpace(data)
+	return string(data)
+}
+
+const (
+	catboxMaxFileSize    = 200 * 1024 * 1024  // 200MB
+	litterboxMaxFileSize = 1024 * 1024 * 1024 // 1GB
+)
+
+var litterboxExpiries = map[string]time.Duration{
+	"1h":  time.Hour,
+	"12h": 12 * time.Hour,
+	"24h": 24 * time.Hour,
+	"72h": 72 * time.Hour,
+}
+
+func ValidateCatboxFile(filePath, litterboxExpiry string) error {
+	st, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if litterboxExpiry != "" {
+		if st.Size() > litterboxMaxFileSize {
+			return fmt.Errorf("file too large (%d bytes > 1GB Litterbox limit)", st.Size())
+		}
+		return nil
+	}
+	if st.Size() > catboxMaxFileSize {
+		return fmt.Errorf("file too large (%d bytes > 200MB limit)", st.Size())
+	}
+	return nil
+}
+
+func uploadFileToCatbox(ctx context.Context, filePath string) (string, error) {
+	fields := [][2]string{{"reqtype", "fileupload"}}
+	if userhash := getCatboxUserhash(); userhash != "" {
+		fields = append(fields, [2]string{"userhash", userhash})
+	}
+	return postCatboxFile(ctx, "https://catbox.moe/user/api.php", fields, filePath)
+}
+
+func isLitterboxURL(rawURL string) bool {
+	parsed, err := neturl.Parse(rawURL)
+	return err == nil && strings.EqualFold(parsed.Hostname(), "litter.catbox.moe")
+}
+
+func uploadFileToLitterbox(ctx context.Context, filePath, expiry string) (string, error) {
+	if _, ok := litterboxExpiries[expiry]; !ok {
+		return "", fmt.Errorf("invalid Litterbox expiry %q (allowed: 1h, 12h, 24h, 72h)", expiry)
+	}
+	fields := [][2]string{{"reqtype", "fileupload"}, {"time", expiry}}
+	return postCatboxFile(ctx, "https://litterbox.catbox.moe/resources/internals/api.php", fields, filePath)
+}
+
+func postCatboxFile(ctx context.Context, apiURL string, fields [][2]string, filePath string) (string, error) {
+	// catbox cannot list uploads, so a failure that may have stored the file
+	// is repeated; a stray copy beats losing the file.
+	var result string
+	err := withUploadRetry(ctx, catboxMaxRetries, func() error {
+		var err error
+		result, err = postCatboxFileOnce(ctx, apiURL, fields, filePath)
+		return err
+	}, nil)
+	return result, err
+}
+
+func postCatboxFileOnce(ctx context.Context, apiURL string, fields [][2]string, filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	contentType := writer.FormDataContentType()
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer pw.Close()
+		defer writer.Close()
+		defer file.Close()
+
+		for _, f := range fields {
+			if err := writer.WriteField(f[0], f[1]); err != nil {
+				pw.CloseWithError(err)
+				errCh <- err
+				return
+			}
+		}
+
+		part, err := writer.CreateFormFile("fileToUpload", filepath.Base(filePath))
+		if err != nil {
+			pw.CloseWithError(err)
+			errCh <- err
+			return
+		}
+
+		bufp := copyBufPool.Get().(*[]byte)
+		_, err = copyFileWithProgress(ctx, part, file, filePath, *bufp)
+		copyBufPool.Put(bufp)
+		if err != nil {
+			pw.CloseWithError(err)
+			errCh <- err
+			return
+		}
+		errCh <- nil
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, pr)
+	if err != nil {
+		pr.Close()
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := sendOnce(req)
+	if err != nil {
+		return "", err
 	}
 	defer resp.Body.Close()
 
+	if pipeErr := <-errCh; pipeErr != nil {
+		return "", fmt.Errorf("failed to write multipart: %w", pipeErr)
+	}
+
+	return readCatboxResult(resp)
+}
+
+// readCatboxResult returns the URL catbox answered with. catbox reports
+// fatal problems such as a disallowed file type as plain text. A cut-off or
+// empty reply or a 5xx status may follow a stored upload and is marked
+// maybeDelivered.
+func readCatboxResult(resp *http.Response) (string, error) {
 	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", maybeDelivered(fmt.Errorf("failed to read response: %w", err))
 	}
 
 	result := strings.TrimSpace(string(body))
-	if !strings.HasPrefix(result, "https://") {
+	switch {
+	case isRetryableStatus(resp.StatusCode):
+		return "", uploadStatusError(resp.StatusCode, fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, result))
+	case result == "":
+		return "", maybeDelivered(fmt.Errorf("upload failed: empty response (status: %d)", resp.StatusCode))
+	case !strings.HasPrefix(result, "https://"):
 		return "", fmt.Errorf("upload failed: %s", result)
 	}
-
 	return result, nil
 }
 
-func createCatboxAlbum(fileNames []string, title, desc string) (string, error) {
+func uploadURLToCatbox(ctx context.Context, targetURL string) (string, error) {
+	var result string
+	err := withUploadRetry(ctx, catboxMaxRetries, func() error {
+		var err error
+		result, err = uploadURLToCatboxOnce(ctx, targetURL)
+		return err
+	}, nil)
+	return result, err
+}
+
+func uploadURLToCatboxOnce(ctx context.Context, targetURL string) (string, error) {
+	userhash := getCatboxUserhash()
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	contentType := writer.FormDataContentType()
+
+	go func() {
+		defer pw.Close()
+		defer writer.Close()
+		writer.WriteField("reqtype", "urlupload")
+		if userhash != "" {
+			writer.WriteField("userhash", userhash)
+		}
+		writer.WriteField("url", targetURL)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://catbox.moe/user/api.php", pr)
+	if err != nil {
+		pr.Close()
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := sendOnce(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	return readCatboxResult(resp)
+}
+
+// createCatboxAlbum only repeats requests that never reached catbox. Albums
+// cannot be listed, and a second copy of the album would be left behind.
+func createCatboxAlbum(ctx context.Context, fileNames []string, title, desc string) (string, error) {
+	var result string
+	err := withRetry(ctx, catboxMaxRetries, func() error {
+		var err error
+		result, err = createCatboxAlbumOnce(ctx, fileNames, title, desc)
+		return err
+	})
+	return result, err
+}
+
+func createCatboxAlbumOnce(ctx context.Context, fileNames []string, title, desc string) (string, error) {
 	pr, pw := io.Pipe()
 	writer := multipart.NewWriter(pw)
 	contentType := writer.FormDataContentType()
 	filesStr := strings.Join(fileNames, " ")
+	userhash := getCatboxUserhash()
 
 	go func() {
 		defer pw.Close()
 		defer writer.Close()
 		writer.WriteField("reqtype", "createalbum")
+		if userhash != "" {
+			writer.WriteField("userhash", userhash)
+		}
 		writer.WriteField("title", title)
 		writer.WriteField("desc", desc)
 		writer.WriteField("files", filesStr)
 	}()
 
-	req, err := http.NewRequest("POST", "https://catbox.moe/user/api.php", pr)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://catbox.moe/user/api.php", pr)
 	if err != nil {
 		pr.Close()
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", contentType)
 
+	resp, err := sendOnce(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	result := strings.TrimSpace(string(body))
+	if result == "" {
+		return "", fmt.Errorf("createalbum failed: empty response (status: %d)", resp.StatusCode)
+	}
+	if !strings.HasPrefix(result, "https://") {
+		return "", fmt.Errorf("createalbum failed: %s", result)
+	}
+	return result, nil
+}
+
+func requireCatboxUserhash() (string, error) {
+	userhash := getCatboxUserhash()
+	if userhash == "" {
+		return "", fmt.Errorf("catbox userhash required. Create catbox.txt next to the executable or enter userhash in UI")
+	}
+	return userhash, nil
+}
+
+func catboxAlbumShort(value string) string {
+	return lastPathSegment(value)
+}
+
+func postCatboxForm(ctx context.Context, fields [][2]string) (string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, f := range fields {
+		if err := writer.WriteField(f[0], f[1]); err != nil {
+			return "", fmt.Errorf("failed to write form: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to write form: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://catbox.moe/user/api.php", &buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
@@ -974,16 +1147,197 @@ func createCatboxAlbum(fileNames []string, title, desc string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
-
 	result := strings.TrimSpace(string(body))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, result)
+	}
+	return result, nil
+}
+
+func deleteCatboxFiles(ctx context.Context, fileNames []string) error {
+	userhash, err := requireCatboxUserhash()
+	if err != nil {
+		return err
+	}
+	if len(fileNames) == 0 {
+		return fmt.Errorf("no files to delete")
+	}
+	result, err := postCatboxForm(ctx, [][2]string{
+		{"reqtype", "deletefiles"},
+		{"userhash", userhash},
+		{"files", strings.Join(fileNames, " ")},
+	})
+	if err != nil {
+		return err
+	}
+	if !isCatboxSuccess(result, "Files successfully deleted") {
+		return fmt.Errorf("deletefiles failed: %s", result)
+	}
+	return nil
+}
+
+// isCatboxSuccess reports whether result is one of the plain-text replies
+// catbox sends on success. Anything else, including error texts that happen
+// to mention success, is a failure.
+func isCatboxSuccess(result string, known ...string) bool {
+	result = strings.TrimSuffix(strings.TrimSpace(result), ".")
+	for _, k := range known {
+		if strings.EqualFold(result, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func catboxAlbumRequest(ctx context.Context, reqtype, short string, fields ...[2]string) (string, error) {
+	userhash, err := requireCatboxUserhash()
+	if err != nil {
+		return "", err
+	}
+	short = catboxAlbumShort(short)
+	if short == "" {
+		return "", fmt.Errorf("album short code is required")
+	}
+	form := append([][2]string{{"reqtype", reqtype}, {"userhash", userhash}, {"short", short}}, fields...)
+	result, err := postCatboxForm(ctx, form)
+	if err != nil {
+		return "", err
+	}
+	if reqtype == "deletealbum" {
+		if !isCatboxSuccess(result, "", "Album deleted", "Album successfully deleted") {
+			return "", fmt.Errorf("%s failed: %s", reqtype, result)
+		}
+		return result, nil
+	}
+	if !strings.HasPrefix(result, "https://") {
+		return "", fmt.Errorf("%s failed: %s", reqtype, result)
+	}
 	return result, nil
 }
 
+func editCatboxAlbum(ctx context.Context, short, title, desc string, fileNames []string) (string, error) {
+	return catboxAlbumRequest(ctx, "editalbum", short, [2]string{"title", title}, [2]string{"desc", desc}, [2]string{"files", strings.Join(fileNames, " ")})
+}
+
+func addToCatboxAlbum(ctx context.Context, short string, fileNames []string) (string, error) {
+	return catboxAlbumRequest(ctx, "addtoalbum", short, [2]string{"files", strings.Join(fileNames, " ")})
+}
+
+func removeFromCatboxAlbum(ctx context.Context, short string, fileNames []string) (string, error) {
+	return catboxAlbumRequest(ctx, "removefromalbum", short, [2]string{"files", strings.Join(fileNames, " ")})
+}
+
+func deleteCatboxAlbum(ctx context.Context, short string) error {
+	_, err := catboxAlbumRequest(ctx, "deletealbum", short)
+	return err
+}
+
+var (
+	customSxcuSubdomain   string
+	customSxcuUploadToken string
+)
+
+func SetSxcuSubdomain(subdomain, uploadToken string) {
+	customSxcuSubdomain = subdomain
+	customSxcuUploadToken = uploadToken
+}
+
+// getSxcuSubdomain returns the configured subdomain and upload token, falling
+// back to sxcu.txt with the subdomain on the first line and the token on the
+// second.
+func getSxcuSubdomain() (string, string) {
+	if customSxcuSubdomain != "" {
+		return customSxcuSubdomain, customSxcuUploadToken
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", ""
+	}
+	exeDir := filepath.Dir(exePath)
+	configFile := filepath.Join(exeDir, "..", "sxcu.txt")
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return "", ""
+	}
+
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	subdomain := strings.TrimSpace(lines[0])
+	var uploadToken string
+	if len(lines) > 1 {
+		uploadToken = strings.TrimSpace(lines[1])
+	}
+	return subdomain, uploadToken
+}
+
+// normalizeSxcuSubdomain accepts "team", "team.sxcu.net" or a URL and returns
+// the name used by the subdomain API and the host to upload to.
+func normalizeSxcuSubdomain(value string) (string, string) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if i := strings.Index(value, "://"); i >= 0 {
+		value = value[i+3:]
+	}
+	if i := strings.IndexAny(value, "/?#"); i >= 0 {
+		value = value[:i]
+	}
+	if value == "" {
+		return "", ""
+	}
+	if !strings.Contains(value, ".") {
+		return value, value + ".sxcu.net"
+	}
+	return strings.TrimSuffix(value, ".sxcu.net"), value
+}
+
 type SxcuResponse struct {
-	ID    string `json:"id"`
-	URL   string `json:"url"`
-	Error string `json:"error"`
-	Code  int    `json:"code"`
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	DelURL string `json:"del_url"`
+	Thumb  string `json:"thumb"`
+	Error  string `json:"error"`
+	Code   int    `json:"code"`
+}
+
+type SxcuUploadOptions struct {
+	CollectionID    string
+	CollectionToken string
+	Subdomain       string
+	UploadToken     string
+	OG              SxcuOGProperties
+	SelfDestruct    bool
+}
+
+const sxcuSelfDestructAfter = 24 * time.Hour
+
+func (o SxcuUploadOptions) uploadURL() string {
+	if o.Subdomain == "" {
+		return "https://sxcu.net/api/files/create"
+	}
+	_, host := normalizeSxcuSubdomain(o.Subdomain)
+	return "https://" + host + "/api/files/create"
+}
+
+func sxcuUploadError(result *SxcuResponse, opts SxcuUploadOptions) error {
+	switch result.Code {
+	case 3:
+		return fmt.Errorf("uploads are not allowed through %s (code: %d)", opts.Subdomain, result.Code)
+	case 806:
+		return fmt.Errorf("sxcu rejected the embed properties as malformed JSON (code: %d)", result.Code)
+	case 807:
+		return fmt.Errorf("embed properties are too long; shorten the title, description or site name (code: %d)", result.Code)
+	case 810:
+		return fmt.Errorf("the collection token is not valid for collection %s (code: %d)", opts.CollectionID, result.Code)
+	case 811:
+		return fmt.Errorf("collection %s is private; a collection token is required (code: %d)", opts.CollectionID, result.Code)
+	case 812:
+		return fmt.Errorf("collection %s not found (code: %d)", opts.CollectionID, result.Code)
+	case 808:
+		return fmt.Errorf("subdomain %s is private; an upload token is required (code: %d)", opts.Subdomain, result.Code)
+	case 809:
+		return fmt.Errorf("the upload token is not valid for subdomain %s (code: %d)", opts.Subdomain, result.Code)
+	}
+	return fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)
 }
 
 type SxcuCollectionOptions struct {
@@ -1009,356 +1363,517 @@ func (r *SxcuCollectionResponse) GetURL() string {
 	return ""
 }
 
-func uploadFileToSxcu(filePath, collectionID, collectionToken string, maxRetries int) (*SxcuResponse, error) {
+func uploadFileToSxcu(ctx context.Context, filePath string, opts SxcuUploadOptions, maxRetries int) (*SxcuResponse, error) {
+	return uploadFileToSxcuWithRateLimitInfo(ctx, filePath, opts, maxRetries, nil)
+}
+
+func uploadFileToSxcuWithRateLimitInfo(ctx context.Context, filePath string, opts SxcuUploadOptions, maxRetries int, onRateLimitWait func(waitMs int64, bucket string)) (*SxcuResponse, error) {
 	if !isSxcuAllowedFileType(filePath) {
 		ext := filepath.Ext(filePath)
 		return nil, fmt.Errorf("file type '%s' is not allowed for sxcu.net", ext)
 	}
+	if err := opts.OG.Validate(); err != nil {
+		return nil, err
+	}
+	ogProperties, err := opts.OG.encode()
+	if err != nil {
+		return nil, err
+	}
 
-	var lastErr error
 	fileName := filepath.Base(filePath)
+	header := http.Header{"User-Agent": {"ImageUploader/1.0 (+https://github.com)"}}
+
+	var result SxcuResponse
+	_, err = executeRateLimited(ctx, rateLimitedRequest{
+		Limiter:    rateLimits.sxcu(sxcuFileUploadBucket),
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: maxRetries,
+		OnWait:     onRateLimitWait,
+		Send: func() (*http.Response, error) {
+			return sendMultipart(ctx, opts.uploadURL(), header, func(writer *multipart.Writer) error {
+				part, err := writer.CreateFormFile("file", fileName)
+				if err != nil {
+					return err
+				}
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		check := checkSxcuRateLimit(sxcuFileUploadBucket)
-		if !check.Allowed {
-			if attempt >= maxRetries {
-				return nil, fmt.Errorf("rate limit exceeded, retry after %dms", check.WaitMs)
-			}
-			time.Sleep(time.Duration(check.WaitMs) * time.Millisecond)
-			continue
-		}
-
-		pr, pw := io.Pipe()
-		writer := multipart.NewWriter(pw)
-		contentType := writer.FormDataContentType()
+				file, err := os.Open(filePath)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
 
-		errCh := make(chan error, 1)
-		go func() {
-			defer pw.Close()
-			defer writer.Close()
+				bufp := copyBufPool.Get().(*[]byte)
+				_, err = copyFileWithProgress(ctx, part, file, filePath, *bufp)
+				copyBufPool.Put(bufp)
+				if err != nil {
+					return err
+				}
 
-			part, err := writer.CreateFormFile("file", fileName)
-			if err != nil {
-				pw.CloseWithError(err)
-				errCh <- err
-				return
+				writer.WriteField("noembed", "")
+				if opts.SelfDestruct {
+					writer.WriteField("self_destruct", "")
+				}
+				if ogProperties != "" {
+					writer.WriteField("og_properties", ogProperties)
+				}
+				if opts.UploadToken != "" {
+					writer.WriteField("token", opts.UploadToken)
+				}
+				if opts.CollectionID != "" {
+					writer.WriteField("collection", opts.CollectionID)
+				}
+				if opts.CollectionToken != "" {
+					writer.WriteField("collection_token", opts.CollectionToken)
+				}
+				return nil
+			})
+		},
+		Classify: func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome {
+			result = SxcuResponse{}
+			if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
+				return rateLimitOutcome{Err: fmt.Errorf("failed to parse response: %w", err)}
 			}
-
-			file, err := os.Open(filePath)
-			if err != nil {
-				pw.CloseWithError(err)
-				errCh <- err
-				return
+			if outcome := sxcuOutcome(resp, headers, result.Error, result.Code, 815, 185); outcome.Limited {
+				return outcome
 			}
-			defer file.Close()
-
-			bufp := copyBufPool.Get().(*[]byte)
-			_, err = io.CopyBuffer(part, file, *bufp)
-			copyBufPool.Put(bufp)
-			if err != nil {
-				pw.CloseWithError(err)
-				errCh <- err
-				return
+			if result.Error != "" {
+				return rateLimitOutcome{Err: sxcuUploadError(&result, opts)}
 			}
+			return rateLimitOutcome{}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
 
-			writer.WriteField("noembed", "")
-			if collectionID != "" {
-				writer.WriteField("collection", collectionID)
+func createSxcuCollection(ctx context.Context, title, desc string, opts SxcuCollectionOptions, maxRetries int) (*SxcuCollectionResponse, error) {
+	header := http.Header{"User-Agent": {"ImageUploader/1.0 (+https://github.com)"}}
+
+	var result SxcuCollectionResponse
+	_, err := executeRateLimited(ctx, rateLimitedRequest{
+		Limiter:    rateLimits.sxcu(sxcuCollectionBucket),
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: maxRetries,
+		Send: func() (*http.Response, error) {
+			return sendMultipart(ctx, "https://sxcu.net/api/collections/create", header, func(writer *multipart.Writer) error {
+				writer.WriteField("title", title)
+				writer.WriteField("desc", desc)
+				writer.WriteField("private", strconv.FormatBool(opts.Private))
+				writer.WriteField("unlisted", strconv.FormatBool(opts.Unlisted))
+				return nil
+			})
+		},
+		Classify: func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome {
+			result = SxcuCollectionResponse{}
+			if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
+				return rateLimitOutcome{Err: fmt.Errorf("failed to parse response: %w", err)}
 			}
-			if collectionToken != "" {
-				writer.WriteField("collection_token", collectionToken)
+			if outcome := sxcuOutcome(resp, headers, result.Error, result.Code, 19); outcome.Limited {
+				return outcome
 			}
-			errCh <- nil
-		}()
+			if result.Error != "" {
+				return rateLimitOutcome{Err: fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)}
+			}
+			return rateLimitOutcome{}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
 
-		req, err := http.NewRequest("POST", "https://sxcu.net/api/files/create", pr)
-		if err != nil {
-			pr.Close()
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-		req.Header.Set("Content-Type", contentType)
-		req.Header.Set("User-Agent", "ImageUploader/1.0 (+https://github.com)")
+type SxcuDeleteResponse struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+	Code    int    `json:"code"`
+}
 
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			backoff := calculateExponentialBackoff(attempt, 1000, 120000)
-			time.Sleep(backoff)
-			continue
+// parseSxcuDeleteURL extracts the object ID and deletion token from a del_url
+// such as https://sxcu.net/api/files/delete/{id}/{token} or
+// https://cancer-co.de/d/{id}/{token}.
+func parseSxcuDeleteURL(delURL string) (string, string, error) {
+	u, err := neturl.Parse(strings.TrimSpace(delURL))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid deletion URL: %w", err)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, segment := range segments {
+		if (segment == "delete" || segment == "d") && i+2 < len(segments) && segments[i+1] != "" && segments[i+2] != "" {
+			return segments[i+1], segments[i+2], nil
 		}
+	}
+	return "", "", fmt.Errorf("no deletion URL recorded")
+}
 
-		headers := parseRateLimitHeaders(resp)
+func deleteSxcuFile(ctx context.Context, fileID, token string, maxRetries int) error {
+	return deleteSxcuObject(ctx, "files", sxcuFileDeleteBucket, fileID, token, maxRetries)
+}
 
-		if pipeErr := <-errCh; pipeErr != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("failed to write multipart: %w", pipeErr)
-		}
+func deleteSxcuLink(ctx context.Context, linkID, token string, maxRetries int) error {
+	return deleteSxcuObject(ctx, "links", sxcuLinkDeleteBucket, linkID, token, maxRetries)
+}
 
-		var result SxcuResponse
-		if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("failed to parse response: %w", err)
-		}
-		resp.Body.Close()
+func deleteSxcuObject(ctx context.Context, kind, bucket, objectID, token string, maxRetries int) error {
+	if objectID == "" || token == "" {
+		return fmt.Errorf("ID and deletion token are required")
+	}
 
-		isGlobalError := resp.StatusCode == 429 && (headers.IsGlobal || result.Code == 2)
-		isRateLimitError := resp.StatusCode == 429 || result.Code == 815 || result.Code == 185
+	apiURL := fmt.Sprintf("https://sxcu.net/api/%s/delete/%s/%s", kind, neturl.PathEscape(objectID), neturl.PathEscape(token))
 
-		updateSxcuRateLimit(sxcuFileUploadBucket, headers, isGlobalError, isRateLimitError)
+	_, err := executeRateLimited(ctx, rateLimitedRequest{
+		Limiter:    rateLimits.sxcu(bucket),
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: maxRetries,
+		Send: func() (*http.Response, error) {
+			req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Header.Set("User-Agent", "ImageUploader/1.0 (+https://github.com)")
+			return sendRequest(req)
+		},
+		Classify: func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome {
+			var result SxcuDeleteResponse
+			if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
+				return rateLimitOutcome{Err: fmt.Errorf("failed to parse response: %w", err)}
+			}
+			if outcome := sxcuOutcome(resp, headers, result.Error, result.Code, 104); outcome.Limited {
+				return outcome
+			}
 
-		if isRateLimitError {
-			if attempt < maxRetries {
-				check := checkSxcuRateLimit(sxcuFileUploadBucket)
-				waitMs := check.WaitMs
-				if waitMs <= 0 {
-					waitMs = int64(calculateExponentialBackoff(attempt, 1000, 120000) / time.Millisecond)
-				}
-				time.Sleep(time.Duration(waitMs) * time.Millisecond)
-				lastErr = fmt.Errorf("rate limit hit: %s (code: %d)", result.Error, result.Code)
-				continue
+			switch result.Code {
+			case 0:
+			case 101, 102:
+				return rateLimitOutcome{Err: fmt.Errorf("not found; it may already be deleted (code: %d)", result.Code)}
+			case 103:
+				return rateLimitOutcome{Err: fmt.Errorf("missing ID or deletion token (code: %d)", result.Code)}
+			default:
+				return rateLimitOutcome{Err: fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)}
 			}
-			return nil, fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)
-		}
+			if result.Error != "" || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return rateLimitOutcome{Err: fmt.Errorf("API error: %s (status: %d)", result.Error, resp.StatusCode)}
+			}
+			return rateLimitOutcome{}
+		},
+	})
+	return err
+}
 
-		if result.Error != "" {
-			return nil, fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)
-		}
+const (
+	sxcuLinkMaxLength   = 1500
+	pasteMaxLength      = 8000000
+	cancerCodeUploadURL = "https://cancer-co.de/upload"
+)
 
-		return &result, nil
+func createSxcuLink(ctx context.Context, link string, maxRetries int) (*SxcuResponse, error) {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return nil, fmt.Errorf("link is empty")
 	}
-
-	if lastErr != nil {
-		return nil, lastErr
+	if n := utf8.RuneCountInString(link); n > sxcuLinkMaxLength {
+		return nil, fmt.Errorf("link is %d characters; the limit is %d", n, sxcuLinkMaxLength)
 	}
-	return nil, fmt.Errorf("max retries exceeded")
-}
 
-func uploadFileToSxcuWithRateLimitInfo(filePath, collectionID, collectionToken string, maxRetries int, onRateLimitWait func(waitMs int64, bucket string)) (*SxcuResponse, error) {
-	if !isSxcuAllowedFileType(filePath) {
-		ext := filepath.Ext(filePath)
-		return nil, fmt.Errorf("file type '%s' is not allowed for sxcu.net", ext)
-	}
+	form := neturl.Values{"link": {link}}.Encode()
 
-	var lastErr error
-	fileName := filepath.Base(filePath)
+	var result SxcuResponse
+	_, err := executeRateLimited(ctx, rateLimitedRequest{
+		Limiter:    rateLimits.sxcu(sxcuLinkBucket),
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: maxRetries,
+		Send: func() (*http.Response, error) {
+			req, err := http.NewRequestWithContext(ctx, "POST", "https://sxcu.net/api/links/create", strings.NewReader(form))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("User-Agent", "ImageUploader/1.0 (+https://github.com)")
+			return sendRequest(req)
+		},
+		Classify: func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome {
+			result = SxcuResponse{}
+			if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
+				return rateLimitOutcome{Err: fmt.Errorf("failed to parse response: %w", err)}
+			}
+			if outcome := sxcuOutcome(resp, headers, result.Error, result.Code, 53); outcome.Limited {
+				return outcome
+			}
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		check := checkSxcuRateLimit(sxcuFileUploadBucket)
-		if !check.Allowed {
-			if attempt >= maxRetries {
-				return nil, fmt.Errorf("rate limit exceeded, retry after %dms", check.WaitMs)
+			switch result.Code {
+			case 0:
+			case 52:
+				return rateLimitOutcome{Err: fmt.Errorf("link is too long for sxcu (code: %d)", result.Code)}
+			default:
+				return rateLimitOutcome{Err: fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)}
 			}
-			if onRateLimitWait != nil {
-				bucket := check.Bucket
-				if bucket == "" {
-					bucket = sxcuFileUploadBucket
-				}
-				onRateLimitWait(check.WaitMs, bucket)
-			} else {
-				time.Sleep(time.Duration(check.WaitMs) * time.Millisecond)
+			if result.URL == "" {
+				return rateLimitOutcome{Err: fmt.Errorf("missing link in response (status: %d)", resp.StatusCode)}
 			}
-			continue
-		}
+			return rateLimitOutcome{}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
 
-		pr, pw := io.Pipe()
-		writer := multipart.NewWriter(pw)
-		contentType := writer.FormDataContentType()
+func uploadPasteFile(ctx context.Context, filePath string, maxRetries int) (*SxcuResponse, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("not a UTF-8 text file")
+	}
+	text := string(data)
+	if n := utf8.RuneCountInString(text); n > pasteMaxLength {
+		return nil, fmt.Errorf("text is %d characters; the limit is %d", n, pasteMaxLength)
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("file is empty")
+	}
 
-		errCh := make(chan error, 1)
-		go func() {
-			defer pw.Close()
-			defer writer.Close()
+	body := neturl.Values{"text": {text}}.Encode()
 
-			part, err := writer.CreateFormFile("file", fileName)
+	var result SxcuResponse
+	_, err = executeRateLimited(ctx, rateLimitedRequest{
+		Limiter:    rateLimits.sxcu(sxcuPasteBucket),
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: maxRetries,
+		Send: func() (*http.Response, error) {
+			req, err := http.NewRequestWithContext(ctx, "POST", cancerCodeUploadURL, strings.NewReader(body))
 			if err != nil {
-				pw.CloseWithError(err)
-				errCh <- err
-				return
+				return nil, fmt.Errorf("failed to create request: %w", err)
 			}
-
-			file, err := os.Open(filePath)
-			if err != nil {
-				pw.CloseWithError(err)
-				errCh <- err
-				return
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("User-Agent", "ImageUploader/1.0 (+https://github.com)")
+			return sendRequest(req)
+		},
+		Classify: func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome {
+			result = SxcuResponse{}
+			if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
+				return rateLimitOutcome{Err: fmt.Errorf("failed to parse response: %w", err)}
 			}
-			defer file.Close()
-
-			bufp := copyBufPool.Get().(*[]byte)
-			_, err = io.CopyBuffer(part, file, *bufp)
-			copyBufPool.Put(bufp)
-			if err != nil {
-				pw.CloseWithError(err)
-				errCh <- err
-				return
+			if outcome := sxcuOutcome(resp, headers, result.Error, result.Code); outcome.Limited {
+				return outcome
 			}
 
-			writer.WriteField("noembed", "")
-			if collectionID != "" {
-				writer.WriteField("collection", collectionID)
+			switch result.Code {
+			case 0:
+			case 61:
+				return rateLimitOutcome{Err: fmt.Errorf("text is too long for cancer-co.de (code: %d)", result.Code)}
+			case 62:
+				return rateLimitOutcome{Err: fmt.Errorf("no text sent (code: %d)", result.Code)}
+			default:
+				return rateLimitOutcome{Err: fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)}
 			}
-			if collectionToken != "" {
-				writer.WriteField("collection_token", collectionToken)
+			if result.URL == "" {
+				return rateLimitOutcome{Err: fmt.Errorf("missing paste URL in response (status: %d)", resp.StatusCode)}
 			}
-			errCh <- nil
-		}()
-
-		req, err := http.NewRequest("POST", "https://sxcu.net/api/files/create", pr)
-		if err != nil {
-			pr.Close()
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-		req.Header.Set("Content-Type", contentType)
-		req.Header.Set("User-Agent", "ImageUploader/1.0 (+https://github.com)")
-
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			backoff := calculateExponentialBackoff(attempt, 1000, 120000)
-			time.Sleep(backoff)
-			continue
-		}
-
-		headers := parseRateLimitHeaders(resp)
-
-		if pipeErr := <-errCh; pipeErr != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("failed to write multipart: %w", pipeErr)
-		}
-
-		var result SxcuResponse
-		if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("failed to parse response: %w", err)
-		}
-		resp.Body.Close()
+			return rateLimitOutcome{}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tracker := progressTrackerFromContext(ctx); tracker != nil {
+		tracker.begin(filePath)
+		tracker.add(filePath, int64(len(data)), int64(len(data)), true)
+	}
+	return &result, nil
+}
 
-		isGlobalError := resp.StatusCode == 429 && (headers.IsGlobal || result.Code == 2)
-		isRateLimitError := resp.StatusCode == 429 || result.Code == 815 || result.Code == 185
+func isPasteURL(rawURL string) bool {
+	u, err := neturl.Parse(rawURL)
+	return err == nil && strings.EqualFold(u.Hostname(), "cancer-co.de")
+}
 
-		updateSxcuRateLimit(sxcuFileUploadBucket, headers, isGlobalError, isRateLimitError)
+func deletePaste(ctx context.Context, pasteID, token string, maxRetries int) error {
+	if pasteID == "" || token == "" {
+		return fmt.Errorf("paste ID and deletion token are required")
+	}
+	apiURL := fmt.Sprintf("https://cancer-co.de/d/%s/%s", neturl.PathEscape(pasteID), neturl.PathEscape(token))
 
-		if isRateLimitError {
-			if attempt < maxRetries {
-				check := checkSxcuRateLimit(sxcuFileUploadBucket)
-				waitMs := check.WaitMs
-				if waitMs <= 0 {
-					waitMs = int64(calculateExponentialBackoff(attempt, 1000, 120000) / time.Millisecond)
-				}
-				bucket := check.Bucket
-				if bucket == "" {
-					bucket = sxcuFileUploadBucket
-				}
-				if onRateLimitWait != nil {
-					onRateLimitWait(waitMs, bucket)
-				} else {
-					time.Sleep(time.Duration(waitMs) * time.Millisecond)
-				}
-				lastErr = fmt.Errorf("rate limit hit: %s (code: %d)", result.Error, result.Code)
-				continue
+	_, err := executeRateLimited(ctx, rateLimitedRequest{
+		Limiter:    rateLimits.sxcu(sxcuPasteDeleteBucket),
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: maxRetries,
+		Send: func() (*http.Response, error) {
+			req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Header.Set("User-Agent", "ImageUploader/1.0 (+https://github.com)")
+			return sendRequest(req)
+		},
+		Classify: func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome {
+			var result SxcuDeleteResponse
+			if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
+				return rateLimitOutcome{Err: fmt.Errorf("failed to parse response: %w", err)}
+			}
+			if outcome := sxcuOutcome(resp, headers, result.Error, result.Code); outcome.Limited {
+				return outcome
 			}
-			return nil, fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)
-		}
-
-		if result.Error != "" {
-			return nil, fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)
-		}
 
-		return &result, nil
-	}
+			switch result.Code {
+			case 0:
+			case 63:
+				return rateLimitOutcome{Err: fmt.Errorf("paste not found; it may already be deleted (code: %d)", result.Code)}
+			case 64:
+				return rateLimitOutcome{Err: fmt.Errorf("missing paste ID or deletion token (code: %d)", result.Code)}
+			default:
+				return rateLimitOutcome{Err: fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)}
+			}
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return rateLimitOutcome{Err: fmt.Errorf("API error: status %d", resp.StatusCode)}
+			}
+			return rateLimitOutcome{}
+		},
+	})
+	return err
+}
 
-	if lastErr != nil {
-		return nil, lastErr
-	}
-	return nil, fmt.Errorf("max retries exceeded")
+type SxcuAPIError struct {
+	Message string `json:"error"`
+	Code    int    `json:"code"`
 }
 
-func createSxcuCollection(title, desc string, opts SxcuCollectionOptions, maxRetries int) (*SxcuCollectionResponse, error) {
-	var lastErr error
+func (e *SxcuAPIError) Error() string {
+	return fmt.Sprintf("API error: %s (code: %d)", e.Message, e.Code)
+}
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		check := checkSxcuRateLimit(sxcuCollectionBucket)
-		if !check.Allowed {
-			if attempt >= maxRetries {
-				return nil, fmt.Errorf("rate limit exceeded, retry after %dms", check.WaitMs)
+func getSxcuJSON(ctx context.Context, path string, out interface{}) error {
+	_, err := executeRateLimited(ctx, rateLimitedRequest{
+		Limiter:    rateLimits.sxcu(""),
+		Policy:     rateLimitPolicy{OnPreFlightBlocked: rateLimitRetry, OnResponse429: rateLimitReturn},
+		MaxRetries: 1,
+		Send: func() (*http.Response, error) {
+			req, err := http.NewRequestWithContext(ctx, "GET", "https://sxcu.net"+path, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Header.Set("User-Agent", "ImageUploader/1.0 (+https://github.com)")
+			return sendRequest(req)
+		},
+		Classify: func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome {
+			body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			if err != nil {
+				return rateLimitOutcome{Err: fmt.Errorf("failed to read response: %w", err)}
 			}
-			time.Sleep(time.Duration(check.WaitMs) * time.Millisecond)
-			continue
-		}
 
-		pr, pw := io.Pipe()
-		writer := multipart.NewWriter(pw)
-		contentType := writer.FormDataContentType()
+			var apiErr SxcuAPIError
+			json.Unmarshal(body, &apiErr)
+			outcome := rateLimitOutcome{
+				Limited: resp.StatusCode == 429,
+				Global:  resp.StatusCode == 429 && (headers.IsGlobal || apiErr.Code == 2),
+			}
 
-		go func() {
-			defer pw.Close()
-			defer writer.Close()
-			writer.WriteField("title", title)
-			writer.WriteField("desc", desc)
-			writer.WriteField("private", strconv.FormatBool(opts.Private))
-			writer.WriteField("unlisted", strconv.FormatBool(opts.Unlisted))
-		}()
+			switch {
+			case apiErr.Message != "" || apiErr.Code != 0:
+				outcome.Err = &apiErr
+			case resp.StatusCode < 200 || resp.StatusCode >= 300:
+				outcome.Err = fmt.Errorf("API error: status %d", resp.StatusCode)
+			default:
+				if err := json.Unmarshal(body, out); err != nil {
+					outcome.Err = fmt.Errorf("failed to parse response: %w", err)
+				}
+			}
+			return outcome
+		},
+	})
+	return err
+}
 
-		req, err := http.NewRequest("POST", "https://sxcu.net/api/collections/create", pr)
-		if err != nil {
-			pr.Close()
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-		req.Header.Set("Content-Type", contentType)
-		req.Header.Set("User-Agent", "ImageUploader/1.0 (+https://github.com)")
+type SxcuSubdomainInfo struct {
+	ID           string `json:"id"`
+	Files        int    `json:"files"`
+	Links        int    `json:"links"`
+	FileViews    int    `json:"file_views"`
+	Public       bool   `json:"public"`
+	Root         bool   `json:"root"`
+	LastActivity int64  `json:"last_activity"`
+}
 
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			backoff := calculateExponentialBackoff(attempt, 1000, 120000)
-			time.Sleep(backoff)
-			continue
-		}
+func validateSxcuSubdomain(ctx context.Context, subdomain, uploadToken string) (*SxcuSubdomainInfo, error) {
+	name, _ := normalizeSxcuSubdomain(subdomain)
+	if name == "" {
+		return nil, fmt.Errorf("subdomain is empty")
+	}
 
-		headers := parseRateLimitHeaders(resp)
+	var exists struct {
+		Exists bool `json:"exists"`
+	}
+	if err := getSxcuJSON(ctx, "/api/subdomains/check/"+neturl.PathEscape(name), &exists); err != nil {
+		return nil, fmt.Errorf("failed to check subdomain %s: %w", name, err)
+	}
+	if !exists.Exists {
+		return nil, fmt.Errorf("subdomain %s does not exist", name)
+	}
 
-		var result SxcuCollectionResponse
-		if err := json.NewDecoder(io.LimitReader(resp.Body, 8192)).Decode(&result); err != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("failed to parse response: %w", err)
+	var info SxcuSubdomainInfo
+	if err := getSxcuJSON(ctx, "/api/subdomains/"+neturl.PathEscape(name), &info); err != nil {
+		var apiErr *SxcuAPIError
+		if errors.As(err, &apiErr) && apiErr.Code == 402 {
+			return nil, fmt.Errorf("subdomain %s does not exist", name)
 		}
-		resp.Body.Close()
-
-		isGlobalError := resp.StatusCode == 429 && (headers.IsGlobal || result.Code == 2)
-		isRateLimitError := resp.StatusCode == 429 || result.Code == 19
+		return nil, fmt.Errorf("failed to look up subdomain %s: %w", name, err)
+	}
+	if !info.Public && uploadToken == "" {
+		return nil, fmt.Errorf("subdomain %s is private; an upload token is required", name)
+	}
+	return &info, nil
+}
 
-		updateSxcuRateLimit(sxcuCollectionBucket, headers, isGlobalError, isRateLimitError)
+func sxcuCollectionID(value string) string {
+	return lastPathSegment(value)
+}
 
-		if isRateLimitError {
-			if attempt < maxRetries {
-				check := checkSxcuRateLimit(sxcuCollectionBucket)
-				waitMs := check.WaitMs
-				if waitMs <= 0 {
-					waitMs = int64(calculateExponentialBackoff(attempt, 1000, 120000) / time.Millisecond)
-				}
-				time.Sleep(time.Duration(waitMs) * time.Millisecond)
-				lastErr = fmt.Errorf("rate limit hit: %s (code: %d)", result.Error, result.Code)
-				continue
-			}
-			return nil, fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)
-		}
+type SxcuCollectionFile struct {
+	ID    string `json:"id"`
+	URL   string `json:"url"`
+	Thumb string `json:"thumb"`
+	Views int    `json:"views"`
+}
 
-		if result.Error != "" {
-			return nil, fmt.Errorf("API error: %s (code: %d)", result.Error, result.Code)
-		}
+type SxcuCollectionInfo struct {
+	ID           string               `json:"id"`
+	Title        string               `json:"title"`
+	Desc         string               `json:"desc"`
+	Views        int                  `json:"views"`
+	CreationTime int64                `json:"creation_time"`
+	Public       bool                 `json:"public"`
+	Unlisted     bool                 `json:"unlisted"`
+	FileViews    int                  `json:"file_views"`
+	Files        []SxcuCollectionFile `json:"files"`
+}
 
-		return &result, nil
+func getSxcuCollection(ctx context.Context, collectionID string) (*SxcuCollectionInfo, error) {
+	id := sxcuCollectionID(collectionID)
+	if id == "" {
+		return nil, fmt.Errorf("collection ID is empty")
 	}
-
-	if lastErr != nil {
-		return nil, lastErr
+	var info SxcuCollectionInfo
+	if err := getSxcuJSON(ctx, "/api/collections/"+neturl.PathEscape(id), &info); err != nil {
+		var apiErr *SxcuAPIError
+		if errors.As(err, &apiErr) && apiErr.Code == 301 {
+			return nil, fmt.Errorf("collection %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to look up collection %s: %w", id, err)
 	}
-	return nil, fmt.Errorf("max retries exceeded")
+	return &info, nil
 }
 
 type ImgchestImage struct {
-	ID   string `json:"id"`
-	Link string `json:"link"`
+	ID           string `json:"id"`
+	Link         string `json:"link"`
+	Description  string `json:"description"`
+	Position     int    `json:"position"`
+	OriginalName string `json:"original_name"`
+	Created      string `json:"created"`
 }
 
 type ImgchestPostResponse struct {
@@ -1425,7 +1940,7 @@ func getImgchestToken() (string, error) {
 	return string(data), nil
 }
 
-type ImgchestBatchCallback func(batchNum int, totalBatches int, postURL string, imageLinks []string, imageIDs []string, err error)
+type ImgchestBatchCallback func(batchNum int, totalBatches int, batch []string, postURL string, imageLinks []string, imageIDs []string, err error)
 
 type ImgchestUploadOptions struct {
 	Title     string
@@ -1460,7 +1975,7 @@ func ValidateImgchestFile(filePath string) error {
 	return nil
 }
 
-func updateImgchestPost(postID string, opts ImgchestUploadOptions, maxRetries int) error {
+func updateImgchestPost(ctx context.Context, postID string, opts ImgchestUploadOptions, maxRetries int) error {
 	if postID == "" {
 		return fmt.Errorf("post ID is required")
 	}
@@ -1495,59 +2010,20 @@ func updateImgchestPost(postID string, opts ImgchestUploadOptions, maxRetries in
 		return fmt.Errorf("failed to marshal update: %w", err)
 	}
 
-	var lastErr error
-
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		check := checkImgchestRateLimit()
-		if !check.Allowed {
-			if attempt >= maxRetries {
-				return fmt.Errorf("rate limit exceeded, retry after %dms", check.WaitMs)
-			}
-			time.Sleep(time.Duration(check.WaitMs) * time.Millisecond)
-		}
-
-		req, err := http.NewRequest("PATCH", apiURL, bytes.NewReader(jsonData))
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "PATCH", apiURL, bytes.NewReader(jsonData))
 		if err != nil {
-			return fmt.Errorf("failed to create request: %w", err)
+			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Accept", "application/json")
 		req.Header.Set("Authorization", authHeader)
+		return sendRequest(req)
+	}
 
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			backoff := calculateExponentialBackoff(attempt, 1000, 120000)
-			time.Sleep(backoff)
-			continue
-		}
-
-		headers := parseRateLimitHeaders(resp)
-		updateImgchestRateLimit(headers)
-
-		if resp.StatusCode == 429 {
-			resp.Body.Close()
-			if attempt < maxRetries {
-				check := checkImgchestRateLimit()
-				waitMs := check.WaitMs
-				if waitMs <= 0 {
-					waitMs = int64(calculateExponentialBackoff(attempt, 1000, 120000) / time.Millisecond)
-				}
-				time.Sleep(time.Duration(waitMs) * time.Millisecond)
-				lastErr = fmt.Errorf("rate limit exceeded")
-				continue
-			}
-			return fmt.Errorf("rate limit exceeded")
-		}
-
-		body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
-		resp.Body.Close()
-		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
-		}
-
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, string(body))
+	return executeImgchest(ctx, maxRetries, send, func(status int, body []byte) error {
+		if status < 200 || status >= 300 {
+			return fmt.Errorf("API error: status=%d body=%s", status, string(body))
 		}
 
 		if len(body) > 0 {
@@ -1567,41 +2043,64 @@ func updateImgchestPost(postID string, opts ImgchestUploadOptions, maxRetries in
 		}
 
 		return nil
-	}
+	})
+}
+
+// writeImgchestImages adds each file as an images[] part.
+func writeImgchestImages(ctx context.Context, writer *multipart.Writer, filePaths []string) error {
+	bufp := copyBufPool.Get().(*[]byte)
+	defer copyBufPool.Put(bufp)
+
+	for _, filePath := range filePaths {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+
+		part, err := writer.CreateFormFile("images[]", filepath.Base(filePath))
+		if err != nil {
+			file.Close()
+			return err
+		}
 
-	if lastErr != nil {
-		return lastErr
+		_, err = copyFileWithProgress(ctx, part, file, filePath, *bufp)
+		file.Close()
+		if err != nil {
+			return err
+		}
 	}
-	return fmt.Errorf("max retries exceeded")
+	return nil
 }
 
-func uploadToImgchestBatch(filePaths []string, opts ImgchestUploadOptions, maxRetries int) (*ImgchestPostResponse, error) {
-	token, err := getImgchestToken()
-	if err != nil {
-		return nil, err
+// readImgchestPostResponse decodes the response of a post create or add.
+func readImgchestPostResponse(status int, body []byte, result *ImgchestPostResponse) error {
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("API error: status=%d body=%s", status, string(body))
 	}
-	authHeader := "Bearer " + token
 
-	var lastErr error
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to parse response: %s", string(body))
+	}
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		check := checkImgchestRateLimit()
-		if !check.Allowed {
-			if attempt >= maxRetries {
-				return nil, fmt.Errorf("rate limit exceeded, retry after %dms", check.WaitMs)
-			}
-			time.Sleep(time.Duration(check.WaitMs) * time.Millisecond)
+	if result.IsFailure() {
+		msg := result.Message
+		if msg == "" {
+			msg = "unknown error"
 		}
+		return fmt.Errorf("API error: %s", msg)
+	}
+	return nil
+}
 
-		pr, pw := io.Pipe()
-		writer := multipart.NewWriter(pw)
-		contentType := writer.FormDataContentType()
-
-		errCh := make(chan error, 1)
-		go func() {
-			defer pw.Close()
-			defer writer.Close()
+func uploadToImgchestBatch(ctx context.Context, filePaths []string, opts ImgchestUploadOptions, maxRetries int) (*ImgchestPostResponse, error) {
+	token, err := getImgchestToken()
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Authorization": {"Bearer " + token}}
 
+	send := func() (*http.Response, error) {
+		return sendMultipart(ctx, "https://api.imgchest.com/v1/post", header, func(writer *multipart.Writer) error {
 			if opts.Title != "" {
 				writer.WriteField("title", opts.Title)
 			}
@@ -1620,113 +2119,26 @@ func uploadToImgchestBatch(filePaths []string, opts ImgchestUploadOptions, maxRe
 			} else {
 				writer.WriteField("anonymous", "0")
 			}
-
-			bufp := copyBufPool.Get().(*[]byte)
-			defer copyBufPool.Put(bufp)
-
-			for _, filePath := range filePaths {
-				file, err := os.Open(filePath)
-				if err != nil {
-					pw.CloseWithError(err)
-					errCh <- err
-					return
-				}
-
-				part, err := writer.CreateFormFile("images[]", filepath.Base(filePath))
-				if err != nil {
-					file.Close()
-					pw.CloseWithError(err)
-					errCh <- err
-					return
-				}
-
-				_, err = io.CopyBuffer(part, file, *bufp)
-				file.Close()
-				if err != nil {
-					pw.CloseWithError(err)
-					errCh <- err
-					return
-				}
-			}
-			errCh <- nil
-		}()
-
-		req, err := http.NewRequest("POST", "https://api.imgchest.com/v1/post", pr)
-		if err != nil {
-			pr.Close()
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-		req.Header.Set("Content-Type", contentType)
-		req.Header.Set("Authorization", authHeader)
-
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			backoff := calculateExponentialBackoff(attempt, 1000, 120000)
-			time.Sleep(backoff)
-			continue
-		}
-
-		headers := parseRateLimitHeaders(resp)
-		updateImgchestRateLimit(headers)
-
-		if pipeErr := <-errCh; pipeErr != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("failed to write multipart: %w", pipeErr)
-		}
-
-		if resp.StatusCode == 429 {
-			resp.Body.Close()
-			if attempt < maxRetries {
-				check := checkImgchestRateLimit()
-				waitMs := check.WaitMs
-				if waitMs <= 0 {
-					waitMs = int64(calculateExponentialBackoff(attempt, 1000, 120000) / time.Millisecond)
-				}
-				time.Sleep(time.Duration(waitMs) * time.Millisecond)
-				lastErr = fmt.Errorf("rate limit exceeded")
-				continue
-			}
-			return nil, fmt.Errorf("rate limit exceeded")
-		}
-
-		body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
-		}
-
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return nil, fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, string(body))
-		}
-
-		var result ImgchestPostResponse
-		if err := json.Unmarshal(body, &result); err != nil {
-			return nil, fmt.Errorf("failed to parse response: %s", string(body))
-		}
-
-		if result.IsFailure() {
-			msg := result.Message
-			if msg == "" {
-				msg = "unknown error"
-			}
-			return nil, fmt.Errorf("API error: %s", msg)
-		}
-
-		return &result, nil
+			return writeImgchestImages(ctx, writer, filePaths)
+		})
 	}
 
-	if lastErr != nil {
-		return nil, lastErr
+	var result ImgchestPostResponse
+	err = executeImgchest(ctx, maxRetries, send, func(status int, body []byte) error {
+		result = ImgchestPostResponse{}
+		return readImgchestPostResponse(status, body, &result)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("max retries exceeded")
+	return &result, nil
 }
 
-func uploadToImgchest(filePaths []string, opts ImgchestUploadOptions, maxRetries int) (*ImgchestPostResponse, error) {
-	return uploadToImgchestWithCallback(filePaths, opts, maxRetries, nil)
+func uploadToImgchest(ctx context.Context, filePaths []string, opts ImgchestUploadOptions, maxRetries int) (*ImgchestPostResponse, error) {
+	return uploadToImgchestWithCallback(ctx, filePaths, opts, maxRetries, nil)
 }
 
-func uploadToImgchestWithCallback(filePaths []string, opts ImgchestUploadOptions, maxRetries int, callback ImgchestBatchCallback) (*ImgchestPostResponse, error) {
+func uploadToImgchestWithCallback(ctx context.Context, filePaths []string, opts ImgchestUploadOptions, maxRetries int, callback ImgchestBatchCallback) (*ImgchestPostResponse, error) {
 	if len(filePaths) == 0 {
 		return nil, fmt.Errorf("no files to upload")
 	}
@@ -1744,10 +2156,10 @@ func uploadToImgchestWithCallback(filePaths []string, opts ImgchestUploadOptions
 	}
 	firstBatch := filePaths[:firstBatchEnd]
 
-	resp, err := uploadToImgchestBatch(firstBatch, opts, maxRetries)
+	resp, err := uploadToImgchestBatch(ctx, firstBatch, opts, maxRetries)
 	if err != nil {
 		if callback != nil {
-			callback(1, totalBatches, "", nil, nil, err)
+			callback(1, totalBatches, firstBatch, "", nil, nil, err)
 		}
 		return nil, err
 	}
@@ -1762,7 +2174,7 @@ func uploadToImgchestWithCallback(filePaths []string, opts ImgchestUploadOptions
 			links = append(links, img.Link)
 			ids = append(ids, img.ID)
 		}
-		callback(1, totalBatches, resp.GetPostURL(), links, ids, nil)
+		callback(1, totalBatches, firstBatch, resp.GetPostURL(), links, ids, nil)
 	}
 
 	if len(filePaths) > batchSize {
@@ -1776,10 +2188,13 @@ func uploadToImgchestWithCallback(filePaths []string, opts ImgchestUploadOptions
 			}
 			batch := filePaths[start:end]
 
-			addResp, err := addToImgchestPost(postID, batch, maxRetries)
+			addResp, err := addToImgchestPost(ctx, postID, batch, maxRetries)
 			if err != nil {
 				if callback != nil {
-					callback(batchNum, totalBatches, resp.GetPostURL(), nil, nil, err)
+					callback(batchNum, totalBatches, batch, resp.GetPostURL(), nil, nil, err)
+				}
+				if ctx.Err() != nil {
+					break
 				}
 				continue
 			}
@@ -1793,7 +2208,7 @@ func uploadToImgchestWithCallback(filePaths []string, opts ImgchestUploadOptions
 					links = append(links, img.Link)
 					ids = append(ids, img.ID)
 				}
-				callback(batchNum, totalBatches, resp.GetPostURL(), links, ids, nil)
+				callback(batchNum, totalBatches, batch, resp.GetPostURL(), links, ids, nil)
 			}
 		}
 	}
@@ -1802,133 +2217,325 @@ func uploadToImgchestWithCallback(filePaths []string, opts ImgchestUploadOptions
 	return resp, nil
 }
 
-func addToImgchestPost(postID string, filePaths []string, maxRetries int) (*ImgchestPostResponse, error) {
+func addToImgchestPost(ctx context.Context, postID string, filePaths []string, maxRetries int) (*ImgchestPostResponse, error) {
 	token, err := getImgchestToken()
 	if err != nil {
 		return nil, err
 	}
-	authHeader := "Bearer " + token
+	header := http.Header{"Authorization": {"Bearer " + token}}
 	apiURL := "https://api.imgchest.com/v1/post/" + postID + "/add"
 
-	var lastErr error
-
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		check := checkImgchestRateLimit()
-		if !check.Allowed {
-			if attempt >= maxRetries {
-				return nil, fmt.Errorf("rate limit exceeded, retry after %dms", check.WaitMs)
-			}
-			time.Sleep(time.Duration(check.WaitMs) * time.Millisecond)
-		}
+	send := func() (*http.Response, error) {
+		return sendMultipart(ctx, apiURL, header, func(writer *multipart.Writer) error {
+			return writeImgchestImages(ctx, writer, filePaths)
+		})
+	}
 
-		pr, pw := io.Pipe()
-		writer := multipart.NewWriter(pw)
-		contentType := writer.FormDataContentType()
+	var result ImgchestPostResponse
+	err = executeImgchest(ctx, maxRetries, send, func(status int, body []byte) error {
+		result = ImgchestPostResponse{}
+		return readImgchestPostResponse(status, body, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
 
-		errCh := make(chan error, 1)
-		go func() {
-			defer pw.Close()
-			defer writer.Close()
+type ImgchestPost struct {
+	ID         string          `json:"id"`
+	Title      string          `json:"title"`
+	Username   string          `json:"username"`
+	Privacy    string          `json:"privacy"`
+	Views      int             `json:"views"`
+	NSFW       int             `json:"nsfw"`
+	ImageCount int             `json:"image_count"`
+	Created    string          `json:"created"`
+	DeleteURL  string          `json:"delete_url"`
+	Images     []ImgchestImage `json:"images"`
+}
 
-			bufp := copyBufPool.Get().(*[]byte)
-			defer copyBufPool.Put(bufp)
+func (p *ImgchestPost) URL() string {
+	return "https://imgchest.com/p/" + p.ID
+}
 
-			for _, filePath := range filePaths {
-				file, err := os.Open(filePath)
-				if err != nil {
-					pw.CloseWithError(err)
-					errCh <- err
-					return
-				}
+func imgchestPostID(value string) string {
+	return lastPathSegment(value)
+}
 
-				part, err := writer.CreateFormFile("images[]", filepath.Base(filePath))
-				if err != nil {
-					file.Close()
-					pw.CloseWithError(err)
-					errCh <- err
-					return
-				}
+// imgchestRequest sends an authenticated JSON request to the imgchest API and
+// decodes the response into out when it is not nil.
+func imgchestRequest(ctx context.Context, method, path string, payload, out interface{}, maxRetries int) error {
+	token, err := getImgchestToken()
+	if err != nil {
+		return err
+	}
+	authHeader := "Bearer " + token
+	apiURL := "https://api.imgchest.com" + path
 
-				_, err = io.CopyBuffer(part, file, *bufp)
-				file.Close()
-				if err != nil {
-					pw.CloseWithError(err)
-					errCh <- err
-					return
-				}
-			}
-			errCh <- nil
-		}()
+	var jsonData []byte
+	if payload != nil {
+		if jsonData, err = json.Marshal(payload); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
 
-		req, err := http.NewRequest("POST", apiURL, pr)
+	send := func() (*http.Response, error) {
+		var body io.Reader
+		if jsonData != nil {
+			body = bytes.NewReader(jsonData)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, apiURL, body)
 		if err != nil {
-			pr.Close()
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
-		req.Header.Set("Content-Type", contentType)
+		if jsonData != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
 		req.Header.Set("Authorization", authHeader)
+		return sendRequest(req)
+	}
 
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			backoff := calculateExponentialBackoff(attempt, 1000, 120000)
-			time.Sleep(backoff)
-			continue
+	return executeImgchest(ctx, maxRetries, send, func(status int, respBody []byte) error {
+		switch {
+		case status == 404:
+			return fmt.Errorf("not found on imgchest")
+		case status == 401 || status == 403:
+			return fmt.Errorf("not allowed; check the API token and that you own it (status: %d)", status)
+		case status < 200 || status >= 300:
+			return fmt.Errorf("API error: status=%d body=%s", status, string(respBody))
 		}
 
-		headers := parseRateLimitHeaders(resp)
-		updateImgchestRateLimit(headers)
-
-		if pipeErr := <-errCh; pipeErr != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("failed to write multipart: %w", pipeErr)
+		var result struct {
+			Success json.RawMessage `json:"success"`
+			Message string          `json:"message"`
 		}
-
-		if resp.StatusCode == 429 {
-			resp.Body.Close()
-			if attempt < maxRetries {
-				check := checkImgchestRateLimit()
-				waitMs := check.WaitMs
-				if waitMs <= 0 {
-					waitMs = int64(calculateExponentialBackoff(attempt, 1000, 120000) / time.Millisecond)
+		if json.Unmarshal(respBody, &result) == nil {
+			if s := string(result.Success); s == "false" || s == `"false"` {
+				msg := result.Message
+				if msg == "" {
+					msg = "unknown error"
 				}
-				time.Sleep(time.Duration(waitMs) * time.Millisecond)
-				lastErr = fmt.Errorf("rate limit exceeded")
-				continue
+				return fmt.Errorf("API request failed: %s", msg)
 			}
-			return nil, fmt.Errorf("rate limit exceeded")
 		}
-
-		body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
+		if out != nil {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
 		}
+		return nil
+	})
+}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return nil, fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, string(body))
-		}
+func getImgchestPost(ctx context.Context, postID string) (*ImgchestPost, error) {
+	id := imgchestPostID(postID)
+	if id == "" {
+		return nil, fmt.Errorf("post ID is required")
+	}
+	var resp struct {
+		Data ImgchestPost `json:"data"`
+	}
+	if err := imgchestRequest(ctx, "GET", "/v1/post/"+neturl.PathEscape(id), nil, &resp, 3); err != nil {
+		return nil, fmt.Errorf("post %s: %w", id, err)
+	}
+	return &resp.Data, nil
+}
 
-		var result ImgchestPostResponse
-		if err := json.Unmarshal(body, &result); err != nil {
-			return nil, fmt.Errorf("failed to parse response: %s", string(body))
-		}
+func deleteImgchestPost(ctx context.Context, postID string) error {
+	id := imgchestPostID(postID)
+	if id == "" {
+		return fmt.Errorf("post ID is required")
+	}
+	if err := imgchestRequest(ctx, "DELETE", "/v1/post/"+neturl.PathEscape(id), nil, nil, 3); err != nil {
+		return fmt.Errorf("post %s: %w", id, err)
+	}
+	return nil
+}
 
-		if result.IsFailure() {
-			msg := result.Message
-			if msg == "" {
-				msg = "unknown error"
-			}
-			return nil, fmt.Errorf("API error: %s", msg)
+// favoriteImgchestPost toggles the post in the user's favorites and returns
+// the API message, such as "Favorite added.".
+func favoriteImgchestPost(ctx context.Context, postID string) (string, error) {
+	id := imgchestPostID(postID)
+	if id == "" {
+		return "", fmt.Errorf("post ID is required")
+	}
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if err := imgchestRequest(ctx, "POST", "/v1/post/"+neturl.PathEscape(id)+"/favorite", nil, &resp, 3); err != nil {
+		return "", fmt.Errorf("post %s: %w", id, err)
+	}
+	return resp.Message, nil
+}
+
+type ImgchestUser struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Created  string `json:"created"`
+}
+
+func (u *ImgchestUser) DisplayName() string {
+	return firstNonEmpty(u.Username, u.Name)
+}
+
+func getImgchestUser(ctx context.Context) (*ImgchestUser, error) {
+	var resp struct {
+		Data ImgchestUser `json:"data"`
+	}
+	if err := imgchestRequest(ctx, "GET", "/v1/users/me", nil, &resp, 3); err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if resp.Data.DisplayName() == "" {
+		return nil, fmt.Errorf("failed to get account: response has no username")
+	}
+	return &resp.Data, nil
+}
+
+// listImgchestUserPosts returns one page of the user's posts, starting at 1,
+// and whether more pages follow.
+func listImgchestUserPosts(ctx context.Context, username string, page int) ([]ImgchestPost, bool, error) {
+	if page < 1 {
+		page = 1
+	}
+	var resp struct {
+		Data []ImgchestPost `json:"data"`
+		Meta struct {
+			CurrentPage int `json:"current_page"`
+			LastPage    int `json:"last_page"`
+		} `json:"meta"`
+	}
+	path := fmt.Sprintf("/v1/user/%s/posts?page=%d", neturl.PathEscape(username), page)
+	if err := imgchestRequest(ctx, "GET", path, nil, &resp, 3); err != nil {
+		return nil, false, fmt.Errorf("failed to list posts: %w", err)
+	}
+	more := len(resp.Data) > 0
+	if resp.Meta.LastPage > 0 {
+		more = page < resp.Meta.LastPage
+	}
+	return resp.Data, more, nil
+}
+
+// imgchestFileID accepts a file ID or its cdn.imgchest.com link, whose name
+// is the ID followed by the extension.
+func imgchestFileID(value string) string {
+	id := lastPathSegment(value)
+	if strings.Contains(value, "/") {
+		id = strings.TrimSuffix(id, filepath.Ext(id))
+	}
+	return id
+}
+
+// imgchestFileIDAt returns the ID of the Image Chest image linked on the line
+// of text that holds pos, a UTF-16 offset as reported by an edit control.
+func imgchestFileIDAt(text string, pos int) string {
+	units := utf16.Encode([]rune(text))
+	if pos < 0 || pos > len(units) {
+		return ""
+	}
+	start, end := pos, pos
+	for start > 0 && units[start-1] != '\n' {
+		start--
+	}
+	for end < len(units) && units[end] != '\r' && units[end] != '\n' {
+		end++
+	}
+	for _, field := range strings.Fields(string(utf16.Decode(units[start:end]))) {
+		if strings.Contains(field, "cdn.imgchest.com/") {
+			return imgchestFileID(field)
 		}
+	}
+	return ""
+}
 
-		return &result, nil
+func getImgchestFile(ctx context.Context, fileID string) (*ImgchestImage, error) {
+	id := imgchestFileID(fileID)
+	if id == "" {
+		return nil, fmt.Errorf("file ID is required")
 	}
+	var resp struct {
+		Data ImgchestImage `json:"data"`
+	}
+	if err := imgchestRequest(ctx, "GET", "/v1/file/"+neturl.PathEscape(id), nil, &resp, 3); err != nil {
+		return nil, fmt.Errorf("file %s: %w", id, err)
+	}
+	return &resp.Data, nil
+}
+
+func updateImgchestFile(ctx context.Context, fileID, description string) error {
+	id := imgchestFileID(fileID)
+	if id == "" {
+		return fmt.Errorf("file ID is required")
+	}
+	payload := map[string]string{"description": description}
+	if err := imgchestRequest(ctx, "PATCH", "/v1/file/"+neturl.PathEscape(id), payload, nil, 3); err != nil {
+		return fmt.Errorf("file %s: %w", id, err)
+	}
+	return nil
+}
+
+// deleteImgchestFile removes a single image and leaves the rest of its post.
+func deleteImgchestFile(ctx context.Context, fileID string) error {
+	id := imgchestFileID(fileID)
+	if id == "" {
+		return fmt.Errorf("file ID is required")
+	}
+	if err := imgchestRequest(ctx, "DELETE", "/v1/file/"+neturl.PathEscape(id), nil, nil, 3); err != nil {
+		return fmt.Errorf("file %s: %w", id, err)
+	}
+	return nil
+}
+
+type ImgchestFileDescription struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// updateImgchestFileDescriptions sets the descriptions of several images with
+// a single bulk request.
+func updateImgchestFileDescriptions(ctx context.Context, files []ImgchestFileDescription, maxRetries int) error {
+	if len(files) == 0 {
+		return nil
+	}
+	payload := struct {
+		Data []ImgchestFileDescription `json:"data"`
+	}{files}
+	if err := imgchestRequest(ctx, "PATCH", "/v1/files", payload, nil, maxRetries); err != nil {
+		return fmt.Errorf("failed to update image descriptions: %w", err)
+	}
+	return nil
+}
+
+// imgchestSidecarDescription returns the contents of photo.jpg.txt or
+// photo.txt next to photo.jpg, or "" when neither exists.
+func imgchestSidecarDescription(filePath string) string {
+	candidates := []string{
+		filePath + ".txt",
+		strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".txt",
+	}
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		if desc := strings.TrimSpace(string(data)); desc != "" {
+			return desc
+		}
+	}
+	return ""
+}
 
-	if lastErr != nil {
-		return nil, lastErr
+// lastPathSegment returns what follows the last slash of a link's path,
+// ignoring trailing slashes, the query and the fragment, or value itself when
+// it is already a bare ID.
+func lastPathSegment(value string) string {
+	value = strings.TrimSpace(value)
+	if u, err := neturl.Parse(value); err == nil {
+		value = u.Path
 	}
-	return nil, fmt.Errorf("max retries exceeded")
+	value = strings.TrimRight(value, "/")
+	return value[strings.LastIndex(value, "/")+1:]
 }
 
 func extractCatboxFilename(url string) string {
@@ -1940,8 +2547,13 @@ func extractCatboxFilename(url string) string {
 }
 
 func main() {
-	app := NewApp()
-	if err := app.Run(); err != nil {
+	if isCLIInvocation(os.Args[1:]) {
+		attachParentConsole()
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
+	if err := runGUI(os.Args[1:]); err != nil {
 		showError(err.Error())
+		os.Exit(exitFailure)
 	}
 }
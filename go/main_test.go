@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCatboxFileSizeLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.mp4")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(catboxMaxFileSize + 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := ValidateCatboxFile(path, ""); err == nil {
+		t.Error("catbox accepted a file over 200MB")
+	}
+	if err := ValidateCatboxFile(path, "1h"); err != nil {
+		t.Errorf("Litterbox rejected a file under 1GB: %v", err)
+	}
+	if err := os.Truncate(path, litterboxMaxFileSize+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCatboxFile(path, "72h"); err == nil {
+		t.Error("Litterbox accepted a file over 1GB")
+	}
+}
+
+func TestIsLitterboxURL(t *testing.T) {
+	if !isLitterboxURL("https://litter.catbox.moe/abc123.png") {
+		t.Error("litter.catbox.moe not recognised")
+	}
+	if isLitterboxURL("https://files.catbox.moe/abc123.png") {
+		t.Error("files.catbox.moe treated as Litterbox")
+	}
+}
+
+func TestCatboxDeleteUploadsSkipsLitterbox(t *testing.T) {
+	entries := []HistoryEntry{
+		{Provider: "catbox", URL: "https://litter.catbox.moe/abc123.png"},
+		{Provider: "catbox", URL: "https://litter.catbox.moe/def456.png"},
+	}
+	deleted, err := catboxProvider{}.DeleteUploads(context.Background(), entries)
+	if len(deleted) != 0 {
+		t.Errorf("deleted = %v, want none", deleted)
+	}
+	if err == nil || !strings.Contains(err.Error(), "abc123.png: Litterbox") || !strings.Contains(err.Error(), "def456.png: Litterbox") {
+		t.Errorf("error = %v, want one Litterbox error per entry", err)
+	}
+}
+
+func TestIsCatboxSuccess(t *testing.T) {
+	tests := []struct {
+		result string
+		want   bool
+	}{
+		{"", true},
+		{"Album deleted.", true},
+		{"album deleted", true},
+		{"Album deletion unsuccessful.", false},
+		{"No album found for user specified.", false},
+	}
+	for _, tt := range tests {
+		if got := isCatboxSuccess(tt.result, "", "Album deleted", "Album successfully deleted"); got != tt.want {
+			t.Errorf("isCatboxSuccess(%q) = %v, want %v", tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestParseSxcuDeleteURL(t *testing.T) {
+	id, token, err := parseSxcuDeleteURL("https://sxcu.net/api/files/delete/5Ab2x/9f8e7d6c")
+	if err != nil || id != "5Ab2x" || token != "9f8e7d6c" {
+		t.Fatalf("parseSxcuDeleteURL() = %q, %q, %v", id, token, err)
+	}
+	id, token, err = parseSxcuDeleteURL("https://cancer-co.de/d/paste1/tok")
+	if err != nil || id != "paste1" || token != "tok" || !isPasteURL("https://cancer-co.de/d/paste1/tok") {
+		t.Fatalf("paste delete URL = %q, %q, %v", id, token, err)
+	}
+	if _, _, err := parseSxcuDeleteURL("https://sxcu.net/5Ab2x.png"); err == nil {
+		t.Error("accepted a file URL without a deletion token")
+	}
+}
+
+func TestNormalizeSxcuSubdomain(t *testing.T) {
+	tests := []struct{ in, name, host string }{
+		{"Team", "team", "team.sxcu.net"},
+		{"team.sxcu.net", "team", "team.sxcu.net"},
+		{"https://team.sxcu.net/abc.png", "team", "team.sxcu.net"},
+		{"i.example.com", "i.example.com", "i.example.com"},
+		{" ", "", ""},
+	}
+	for _, tt := range tests {
+		if name, host := normalizeSxcuSubdomain(tt.in); name != tt.name || host != tt.host {
+			t.Errorf("normalizeSxcuSubdomain(%q) = %q, %q; want %q, %q", tt.in, name, host, tt.name, tt.host)
+		}
+	}
+	if got := (SxcuUploadOptions{Subdomain: "team"}).uploadURL(); got != "https://team.sxcu.net/api/files/create" {
+		t.Errorf("uploadURL() = %q", got)
+	}
+}
+
+func TestImgchestSidecarDescription(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.png.txt", " Full name \n")
+	write("a.txt", "Base name")
+	write("b.txt", "Only base\n")
+	write("c.txt", "  \n")
+
+	for name, want := range map[string]string{"a.png": "Full name", "b.jpg": "Only base", "c.gif": "", "d.png": ""} {
+		if got := imgchestSidecarDescription(filepath.Join(dir, name)); got != want {
+			t.Errorf("imgchestSidecarDescription(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLastPathSegment(t *testing.T) {
+	for value, want := range map[string]string{
+		"pd412w":                          "pd412w",
+		" https://imgchest.com/p/abc123 ": "abc123",
+		"https://sxcu.net/c/coll1//":      "coll1",
+		"https://imgchest.com/p/abc?x=1":  "abc",
+		"https://sxcu.net/c/coll1/#top":   "coll1",
+		"":                                "",
+	} {
+		if got := lastPathSegment(value); got != want {
+			t.Errorf("lastPathSegment(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestImgchestFileID(t *testing.T) {
+	for value, want := range map[string]string{
+		"7kzcajvdwp7": "7kzcajvdwp7",
+		" https://cdn.imgchest.com/files/7kzcajvdwp7.png ": "7kzcajvdwp7",
+		"https://cdn.imgchest.com/files/7kzcajvdwp7.png/":  "7kzcajvdwp7",
+		"": "",
+	} {
+		if got := imgchestFileID(value); got != want {
+			t.Errorf("imgchestFileID(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestImgchestFileIDAt(t *testing.T) {
+	text := "✓ Done\r\nPost: https://imgchest.com/p/abc123\r\nhttps://cdn.imgchest.com/files/7kzcajvdwp7.png\r\nhttps://cdn.imgchest.com/files/q8xz.jpg\r\n"
+	tests := []struct {
+		pos  int
+		want string
+	}{
+		{0, ""},
+		{12, ""},
+		{len("✓ Done\r\nPost: https://imgchest.com/p/abc123\r\n") - 2, "7kzcajvdwp7"},
+		{len(text) - 10, "q8xz"},
+		{len(text) + 10, ""},
+	}
+	for _, tt := range tests {
+		if got := imgchestFileIDAt(text, tt.pos); got != tt.want {
+			t.Errorf("imgchestFileIDAt(%d) = %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestKekAPIErrorPlanRefusal(t *testing.T) {
+	tests := []struct {
+		err     KekAPIError
+		refusal bool
+		message string
+	}{
+		{KekAPIError{Status: 402, Body: ""}, true, "Payment Required"},
+		{KekAPIError{Status: 403, Body: `{"error":"Upgrade your plan to make posts private"}`}, true, "Upgrade your plan to make posts private"},
+		{KekAPIError{Status: 403, Body: `{"message":"Not your post"}`}, false, "Not your post"},
+		{KekAPIError{Status: 401, Body: `{"error":"Invalid API key for this plan"}`}, false, "Invalid API key for this plan"},
+		{KekAPIError{Status: 400, Body: `{"error":"Upgrade to premium"}`}, false, "Upgrade to premium"},
+		{KekAPIError{Status: 500, Body: "plan service down"}, false, "plan service down"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.IsPlanRefusal(); got != tt.refusal {
+			t.Errorf("%v: IsPlanRefusal() = %v, want %v", tt.err.Error(), got, tt.refusal)
+		}
+		if got := tt.err.Message(); got != tt.message {
+			t.Errorf("%v: Message() = %q, want %q", tt.err.Error(), got, tt.message)
+		}
+	}
+}
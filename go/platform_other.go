@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func showError(message string) {
+	fmt.Fprintln(os.Stderr, "Error: "+message)
+}
+
+func showInfo(message string) {
+	fmt.Fprintln(os.Stderr, message)
+}
+
+func attachParentConsole() {}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32                        = syscall.NewLazyDLL("user32.dll")
+	messageBoxW                   = user32.NewProc("MessageBoxW")
+	setProcessDPIAware            = user32.NewProc("SetProcessDPIAware")
+	setProcessDpiAwarenessContext = user32.NewProc("SetProcessDpiAwarenessContext")
+	comdlg32                      = syscall.NewLazyDLL("comdlg32.dll")
+	getOpenFileNameW              = comdlg32.NewProc("GetOpenFileNameW")
+	dwmapi                        = syscall.NewLazyDLL("dwmapi.dll")
+	dwmSetWindowAttr              = dwmapi.NewProc("DwmSetWindowAttribute")
+	advapi32                      = syscall.NewLazyDLL("advapi32.dll")
+	regOpenKeyExW                 = advapi32.NewProc("RegOpenKeyExW")
+	regQueryValueExW              = advapi32.NewProc("RegQueryValueExW")
+	regCloseKey                   = advapi32.NewProc("RegCloseKey")
+	kernel32                      = syscall.NewLazyDLL("kernel32.dll")
+	attachConsole                 = kernel32.NewProc("AttachConsole")
+)
+
+const (
+	DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2 = ^uintptr(4) // -5
+	ATTACH_PARENT_PROCESS                      = ^uintptr(0) // -1
+)
+
+func init() {
+	if setProcessDpiAwarenessContext.Find() == nil {
+		setProcessDpiAwarenessContext.Call(DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2)
+	} else if setProcessDPIAware.Find() == nil {
+		setProcessDPIAware.Call()
+	}
+}
+
+const (
+	MB_OK              = 0x00000000
+	MB_ICONERROR       = 0x00000010
+	MB_ICONINFORMATION = 0x00000040
+
+	DWMWA_USE_IMMERSIVE_DARK_MODE = 20
+
+	HKEY_CURRENT_USER = 0x80000001
+	KEY_READ          = 0x20019
+)
+
+var (
+	titleInfo, _  = syscall.UTF16PtrFromString("Image Uploader")
+	titleError, _ = syscall.UTF16PtrFromString("Image Uploader - Error")
+)
+
+func showError(message string) {
+	msg, _ := syscall.UTF16PtrFromString(message)
+	messageBoxW.Call(0, uintptr(unsafe.Pointer(msg)), uintptr(unsafe.Pointer(titleError)), MB_OK|MB_ICONERROR)
+}
+
+func showInfo(message string) {
+	msg, _ := syscall.UTF16PtrFromString(message)
+	messageBoxW.Call(0, uintptr(unsafe.Pointer(msg)), uintptr(unsafe.Pointer(titleInfo)), MB_OK|MB_ICONINFORMATION)
+}
+
+func IsSystemDarkMode() bool {
+	subKey, _ := syscall.UTF16PtrFromString(`Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`)
+	valueName, _ := syscall.UTF16PtrFromString("AppsUseLightTheme")
+
+	var hKey uintptr
+	ret, _, _ := regOpenKeyExW.Call(HKEY_CURRENT_USER, uintptr(unsafe.Pointer(subKey)), 0, KEY_READ, uintptr(unsafe.Pointer(&hKey)))
+	if ret != 0 {
+		return false
+	}
+	defer regCloseKey.Call(hKey)
+
+	var dataType uint32
+	var data uint32
+	dataSize := uint32(4)
+	ret, _, _ = regQueryValueExW.Call(hKey, uintptr(unsafe.Pointer(valueName)), 0, uintptr(unsafe.Pointer(&dataType)), uintptr(unsafe.Pointer(&data)), uintptr(unsafe.Pointer(&dataSize)))
+	if ret != 0 {
+		return false
+	}
+	return data == 0
+}
+
+// The executable is linked as a GUI program, so it has no console of its own.
+// Borrow the parent's console for CLI output unless the streams were redirected.
+func attachParentConsole() {
+	if attachConsole.Find() != nil {
+		return
+	}
+	if r, _, _ := attachConsole.Call(ATTACH_PARENT_PROCESS); r == 0 {
+		return
+	}
+	if !isValidStdHandle(os.Stdout) {
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			os.Stdout = f
+		}
+	}
+	if !isValidStdHandle(os.Stderr) {
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			os.Stderr = f
+		}
+	}
+}
+
+func isValidStdHandle(f *os.File) bool {
+	fd := f.Fd()
+	return fd != 0 && fd != uintptr(windows.InvalidHandle)
+}
+
+func SetDarkModeTitleBar(hwnd uintptr, dark bool) {
+	if dwmSetWindowAttr.Find() != nil {
+		return
+	}
+	var value int32
+	if dark {
+		value = 1
+	}
+	dwmSetWindowAttr.Call(hwnd, DWMWA_USE_IMMERSIVE_DARK_MODE, uintptr(unsafe.Pointer(&value)), 4)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type UploadProgress struct {
+	File           string
+	FileSent       int64
+	FileSize       int64
+	BytesSent      int64
+	TotalBytes     int64
+	Elapsed        time.Duration
+	BytesPerSecond float64
+	ETA            time.Duration // 0 until the throughput is known
+}
+
+func (p UploadProgress) FilePercent() int {
+	if p.FileSize <= 0 {
+		return 100
+	}
+	return int(p.FileSent * 100 / p.FileSize)
+}
+
+type ProgressFunc func(UploadProgress)
+
+const progressReportInterval = 200 * time.Millisecond
+
+type progressTracker struct {
+	mu         sync.Mutex
+	onProgress ProgressFunc
+	total      int64
+	sent       int64
+	files      map[string]int64
+	start      time.Time
+	lastReport time.Time
+}
+
+func newProgressTracker(totalBytes int64, onProgress ProgressFunc) *progressTracker {
+	return &progressTracker{
+		onProgress: onProgress,
+		total:      totalBytes,
+		files:      make(map[string]int64),
+		start:      timeNow(),
+	}
+}
+
+type progressTrackerKey struct{}
+
+func withProgressTracker(ctx context.Context, t *progressTracker) context.Context {
+	return context.WithValue(ctx, progressTrackerKey{}, t)
+}
+
+func progressTrackerFromContext(ctx context.Context) *progressTracker {
+	t, _ := ctx.Value(progressTrackerKey{}).(*progressTracker)
+	return t
+}
+
+func totalFileSize(paths []string) int64 {
+	var total int64
+	for _, p := range paths {
+		if st, err := os.Stat(p); err == nil {
+			total += st.Size()
+		}
+	}
+	return total
+}
+
+// begin restarts the accounting for filePath so that retried uploads do not
+// count the same bytes twice.
+func (t *progressTracker) begin(filePath string) {
+	t.mu.Lock()
+	t.sent -= t.files[filePath]
+	t.files[filePath] = 0
+	t.mu.Unlock()
+}
+
+func (t *progressTracker) add(filePath string, size, n int64, done bool) {
+	t.mu.Lock()
+	t.files[filePath] += n
+	t.sent += n
+	now := timeNow()
+	if !done && now.Sub(t.lastReport) < progressReportInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.lastReport = now
+
+	p := UploadProgress{
+		File:       filepath.Base(filePath),
+		FileSent:   t.files[filePath],
+		FileSize:   size,
+		BytesSent:  t.sent,
+		TotalBytes: t.total,
+		Elapsed:    now.Sub(t.start),
+	}
+	t.mu.Unlock()
+
+	if secs := p.Elapsed.Seconds(); secs > 0 {
+		p.BytesPerSecond = float64(p.BytesSent) / secs
+	}
+	if p.BytesPerSecond > 0 && p.TotalBytes > p.BytesSent {
+		p.ETA = time.Duration(float64(p.TotalBytes-p.BytesSent) / p.BytesPerSecond * float64(time.Second))
+	}
+	t.onProgress(p)
+}
+
+type progressWriter struct {
+	w        io.Writer
+	tracker  *progressTracker
+	filePath string
+	size     int64
+	written  int64
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.written += int64(n)
+	pw.tracker.add(pw.filePath, pw.size, int64(n), pw.written >= pw.size)
+	return n, err
+}
+
+func copyFileWithProgress(ctx context.Context, dst io.Writer, file *os.File, filePath string, buf []byte) (int64, error) {
+	t := progressTrackerFromContext(ctx)
+	if t == nil {
+		return io.CopyBuffer(dst, file, buf)
+	}
+	var size int64
+	if st, err := file.Stat(); err == nil {
+		size = st.Size()
+	}
+	t.begin(filePath)
+	return io.CopyBuffer(&progressWriter{w: dst, tracker: t, filePath: filePath, size: size}, file, buf)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+func formatETA(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d%time.Hour) / int(time.Minute)
+	s := int(d%time.Minute) / int(time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+func formatProgress(p UploadProgress) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d%% · %s / %s", p.File, p.FilePercent(), formatBytes(p.BytesSent), formatBytes(p.TotalBytes))
+	if p.BytesPerSecond > 0 {
+		fmt.Fprintf(&b, " · %s/s", formatBytes(int64(p.BytesPerSecond)))
+	}
+	if p.ETA > 0 {
+		fmt.Fprintf(&b, " · ETA %s", formatETA(p.ETA))
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCopyFileWithProgressRetryDoesNotDoubleCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.png")
+	if err := os.WriteFile(path, bytes.Repeat([]byte{1}, 10000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var last UploadProgress
+	tracker := newProgressTracker(10000, func(p UploadProgress) { last = p })
+	ctx := withProgressTracker(context.Background(), tracker)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dst bytes.Buffer
+		if _, err := copyFileWithProgress(ctx, &dst, f, path, make([]byte, 1024)); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+
+	if last.BytesSent != 10000 || last.FileSent != 10000 || last.FilePercent() != 100 {
+		t.Fatalf("progress = %+v, want 10000 bytes sent at 100%%", last)
+	}
+	if last.File != "a.png" {
+		t.Fatalf("File = %q, want a.png", last.File)
+	}
+}
+
+func TestFormatProgress(t *testing.T) {
+	p := UploadProgress{
+		File:           "big.mp4",
+		FileSent:       25 << 20,
+		FileSize:       50 << 20,
+		BytesSent:      25 << 20,
+		TotalBytes:     100 << 20,
+		BytesPerSecond: 2 << 20,
+		ETA:            75 * time.Second,
+	}
+	want := "big.mp4 50% · 25.0 MB / 100.0 MB · 2.0 MB/s · ETA 1:15"
+	if got := formatProgress(p); got != want {
+		t.Fatalf("formatProgress() = %q, want %q", got, want)
+	}
+	if got := formatBytes(512); got != "512 B" {
+		t.Fatalf("formatBytes(512) = %q", got)
+	}
+	if got := formatETA(3725 * time.Second); got != "1:02:05" {
+		t.Fatalf("formatETA() = %q", got)
+	}
+}
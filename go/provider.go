@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ProviderCapabilities struct {
+	URLUpload   bool
+	GroupLabel  string // "Album", "Collection", "Post"; empty when uploads cannot be grouped
+	Title       bool
+	Description bool
+	MaxFileSize int64 // 0 when the provider does not document a limit
+}
+
+type UploadJob struct {
+	Files []string
+	URLs  []string
+	Title string
+	Desc  string
+
+	CreateAlbum         bool
+	AlbumShort          string
+	LitterboxExpiry     string // "1h", "12h", "24h" or "72h"; empty for permanent catbox uploads
+	CreateCollection    bool
+	SxcuCollection      SxcuCollectionOptions
+	SxcuCollectionID    string
+	SxcuCollectionToken string
+	SxcuOG              SxcuOGProperties
+	SxcuSelfDestruct    bool
+	SxcuPaste           bool // upload the files as cancer-co.de text pastes instead of sxcu files
+	Imgchest            ImgchestUploadOptions
+	ImageDescriptions   map[string]string // imgchest descriptions keyed by file path
+	PostID              string
+	KekMature           bool
+	KekVisibility       string // "public" or "private"; empty keeps the account default
+}
+
+type UploadResult struct {
+	URL       string
+	ID        string
+	Source    string // local path or source URL
+	DeleteURL string
+	Thumb     string
+	ExpiresAt time.Time
+}
+
+type UploadGroup struct {
+	ID    string
+	URL   string
+	Token string
+}
+
+type UploadSummary struct {
+	Results      []string
+	GroupResult  string
+	Errors       []string
+	SuccessCount int
+	Cancelled    bool
+	Items        []*UploadResult
+	Group        *UploadGroup
+}
+
+type Provider interface {
+	Name() string
+	Capabilities() ProviderCapabilities
+	UploadFile(ctx context.Context, job *UploadJob, filePath string, group *UploadGroup) (*UploadResult, error)
+	UploadURL(ctx context.Context, job *UploadJob, targetURL string) (*UploadResult, error)
+	CreateGroup(ctx context.Context, job *UploadJob, items []*UploadResult) (*UploadGroup, error)
+	Upload(ctx context.Context, job *UploadJob, updateOutput func(string)) UploadSummary
+}
+
+// UploadDeleter is implemented by providers that can delete uploaded files.
+// DeleteUploads returns the entries that were deleted, which may be fewer
+// than requested when err is not nil.
+type UploadDeleter interface {
+	DeleteUploads(ctx context.Context, entries []HistoryEntry) ([]HistoryEntry, error)
+}
+
+// Details lists the deletion and thumbnail links and expiry of each upload.
+func (s UploadSummary) Details() []string {
+	var lines []string
+	for _, item := range s.Items {
+		var parts []string
+		if item.DeleteURL != "" {
+			parts = append(parts, "delete: "+item.DeleteURL)
+		}
+		if item.Thumb != "" {
+			parts = append(parts, "thumb: "+item.Thumb)
+		}
+		if !item.ExpiresAt.IsZero() {
+			parts = append(parts, "expires: "+item.ExpiresAt.Local().Format("2006-01-02 15:04"))
+		}
+		if len(parts) > 0 {
+			lines = append(lines, item.URL+"  "+strings.Join(parts, "  "))
+		}
+	}
+	return lines
+}
+
+var errUnsupported = errors.New("not supported by this provider")
+
+const defaultProviderName = "imgchest"
+
+var providerRegistry []Provider
+
+func init() {
+	RegisterProvider(catboxProvider{})
+	RegisterProvider(sxcuProvider{})
+	RegisterProvider(imgchestProvider{})
+	RegisterProvider(kekProvider{})
+}
+
+func RegisterProvider(p Provider) {
+	if _, exists := LookupProvider(p.Name()); exists {
+		panic(fmt.Sprintf("provider %q registered twice", p.Name()))
+	}
+	providerRegistry = append(providerRegistry, p)
+}
+
+func LookupProvider(name string) (Provider, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, p := range providerRegistry {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+func ProviderNames() []string {
+	names := make([]string, 0, len(providerRegistry))
+	for _, p := range providerRegistry {
+		names = append(names, p.Name())
+	}
+	return names
+}
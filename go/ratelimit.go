@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// rateLimitAction says what the engine does when a request is rate limited.
+type rateLimitAction int
+
+const (
+	rateLimitRetry rateLimitAction = iota
+	rateLimitReturn
+	// rateLimitWait waits out a blocked pre-flight check and then sends in the
+	// same attempt instead of starting the next one.
+	rateLimitWait
+)
+
+// rateLimitPolicy decides separately whether a blocked pre-flight check and a
+// rate-limited response wait and retry or return straight away.
+type rateLimitPolicy struct {
+	OnPreFlightBlocked rateLimitAction
+	OnResponse429      rateLimitAction
+}
+
+var (
+	failFastRateLimitPolicy       = rateLimitPolicy{OnPreFlightBlocked: rateLimitReturn, OnResponse429: rateLimitReturn}
+	retryAfterWaitRateLimitPolicy = rateLimitPolicy{OnPreFlightBlocked: rateLimitRetry, OnResponse429: rateLimitRetry}
+	sendAfterWaitRateLimitPolicy  = rateLimitPolicy{OnPreFlightBlocked: rateLimitWait, OnResponse429: rateLimitRetry}
+)
+
+// rateLimiter is the tracked rate-limit state a request is checked against
+// and recorded in.
+type rateLimiter interface {
+	bucket() string
+	check() RateLimitCheckResult
+	update(headers RateLimitHeaders, isGlobalError, isRateLimitError bool)
+}
+
+type sxcuLimiter struct {
+	tracker *rateLimitTracker
+	route   string
+}
+
+func (l sxcuLimiter) bucket() string {
+	if l.route == "" {
+		return sxcuGlobalBucket
+	}
+	return l.route
+}
+
+func (l sxcuLimiter) check() (result RateLimitCheckResult) {
+	l.tracker.update(func(state *AllRateLimits, nowMs int64) {
+		result = state.checkSxcu(l.route, nowMs)
+	})
+	return result
+}
+
+func (l sxcuLimiter) update(headers RateLimitHeaders, isGlobalError, isRateLimitError bool) {
+	l.tracker.update(func(state *AllRateLimits, nowMs int64) {
+		state.updateSxcu(l.route, headers, isGlobalError, isRateLimitError, nowMs)
+	})
+}
+
+type imgchestLimiter struct {
+	tracker *rateLimitTracker
+}
+
+func (l imgchestLimiter) bucket() string { return "imgchest" }
+
+func (l imgchestLimiter) check() (result RateLimitCheckResult) {
+	l.tracker.update(func(state *AllRateLimits, nowMs int64) {
+		result = state.checkImgchest(nowMs)
+	})
+	return result
+}
+
+func (l imgchestLimiter) update(headers RateLimitHeaders, _, _ bool) {
+	l.tracker.update(func(state *AllRateLimits, nowMs int64) {
+		state.updateImgchest(headers, nowMs)
+	})
+}
+
+// rateLimitOutcome is a classifier's verdict on one response. Err is returned
+// as the result, or for a limited response once no retries are left.
+type rateLimitOutcome struct {
+	Limited bool
+	Global  bool
+	Err     error
+}
+
+type rateLimitedRequest struct {
+	Limiter    rateLimiter
+	Policy     rateLimitPolicy
+	MaxRetries int
+
+	// Send builds and sends a fresh request for every attempt. An error
+	// marked retryable is backed off and retried; any other is returned.
+	Send func() (*http.Response, error)
+
+	// Classify reads the response; the engine closes the body afterwards.
+	Classify func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome
+
+	// OnWait, when set, is told about every rate-limit wait and does the
+	// waiting itself; otherwise the engine sleeps.
+	OnWait func(waitMs int64, bucket string)
+}
+
+// executeRateLimited runs r until a response is not rate limited, the policy
+// says to return, or MaxRetries retries have been spent. It reports how many
+// attempts it made, counting blocked pre-flight checks.
+func executeRateLimited(ctx context.Context, r rateLimitedRequest) (int, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= r.MaxRetries; attempt++ {
+		attempts := attempt + 1
+		final := attempt >= r.MaxRetries
+
+		check := r.Limiter.check()
+		if !check.Allowed {
+			if final || r.Policy.OnPreFlightBlocked == rateLimitReturn {
+				return attempts, fmt.Errorf("rate limit exceeded, retry after %dms", check.WaitMs)
+			}
+			if err := r.wait(ctx, check.WaitMs, check.Bucket); err != nil {
+				return attempts, err
+			}
+			if r.Policy.OnPreFlightBlocked != rateLimitWait {
+				continue
+			}
+		}
+
+		resp, err := r.Send()
+		if err != nil {
+			if !isRetryable(err) {
+				return attempts, err
+			}
+			lastErr = err
+			if !final {
+				if err := sleepContext(ctx, calculateExponentialBackoff(attempt, 1000, 120000)); err != nil {
+					return attempts, err
+				}
+			}
+			continue
+		}
+
+		headers := parseRateLimitHeaders(resp)
+		outcome := r.Classify(resp, headers)
+		resp.Body.Close()
+		r.Limiter.update(headers, outcome.Global, outcome.Limited)
+
+		if !outcome.Limited || final || r.Policy.OnResponse429 == rateLimitReturn {
+			return attempts, outcome.Err
+		}
+		lastErr = outcome.Err
+
+		check = r.Limiter.check()
+		waitMs := check.WaitMs
+		if waitMs <= 0 {
+			waitMs = int64(calculateExponentialBackoff(attempt, 1000, 120000) / time.Millisecond)
+		}
+		if err := r.wait(ctx, waitMs, check.Bucket); err != nil {
+			return attempts, err
+		}
+	}
+
+	if lastErr != nil {
+		return r.MaxRetries + 1, lastErr
+	}
+	return r.MaxRetries + 1, fmt.Errorf("max retries exceeded")
+}
+
+func (r *rateLimitedRequest) wait(ctx context.Context, waitMs int64, bucket string) error {
+	if bucket == "" {
+		bucket = r.Limiter.bucket()
+	}
+	if r.OnWait != nil {
+		r.OnWait(waitMs, bucket)
+		return ctx.Err()
+	}
+	return sleepContext(ctx, time.Duration(waitMs)*time.Millisecond)
+}
+
+// sendRequest sends req, marking transport failures as retryable.
+func sendRequest(req *http.Request) (*http.Response, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, retryable(fmt.Errorf("request failed: %w", err))
+	}
+	return resp, nil
+}
+
+// sendMultipart streams the form written by fill as a POST to apiURL, so each
+// attempt rebuilds the body from the files on disk.
+func sendMultipart(ctx context.Context, apiURL string, header http.Header, fill func(w *multipart.Writer) error) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	errCh := make(chan error, 1)
+	go func() {
+		err := fill(writer)
+		if err == nil {
+			err = writer.Close()
+		}
+		pw.CloseWithError(err)
+		errCh <- err
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, pr)
+	if err != nil {
+		pr.Close()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if pipeErr := <-errCh; pipeErr != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to write multipart: %w", pipeErr)
+	}
+	return resp, nil
+}
+
+// sxcuOutcome classifies an sxcu response as rate limited when it is a 429
+// or carries one of the endpoint's rate-limit codes.
+func sxcuOutcome(resp *http.Response, headers RateLimitHeaders, message string, code int, limitCodes ...int) rateLimitOutcome {
+	limited := resp.StatusCode == 429
+	for _, c := range limitCodes {
+		limited = limited || code == c
+	}
+	if !limited {
+		return rateLimitOutcome{}
+	}
+	return rateLimitOutcome{
+		Limited: true,
+		Global:  resp.StatusCode == 429 && (headers.IsGlobal || code == 2),
+		Err:     fmt.Errorf("API error: %s (code: %d)", message, code),
+	}
+}
+
+// executeImgchest runs send under the imgchest rate limit, retrying 429s, and
+// hands every other response to read. A blocked pre-flight check is waited out
+// without spending an attempt.
+func executeImgchest(ctx context.Context, maxRetries int, send func() (*http.Response, error), read func(status int, body []byte) error) error {
+	_, err := executeRateLimited(ctx, rateLimitedRequest{
+		Limiter:    rateLimits.imgchest(),
+		Policy:     sendAfterWaitRateLimitPolicy,
+		MaxRetries: maxRetries,
+		Send:       send,
+		Classify: func(resp *http.Response, _ RateLimitHeaders) rateLimitOutcome {
+			if resp.StatusCode == 429 {
+				return rateLimitOutcome{Limited: true, Err: fmt.Errorf("rate limit exceeded")}
+			}
+			body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+			if err != nil {
+				return rateLimitOutcome{Err: fmt.Errorf("failed to read response: %w", err)}
+			}
+			return rateLimitOutcome{Err: read(resp.StatusCode, body)}
+		},
+	})
+	return err
+}
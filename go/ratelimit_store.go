@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// rateLimitStore keeps the tracked rate limits. update hands fn the current
+// state and keeps whatever it changes, without other updates interleaving.
+type rateLimitStore interface {
+	update(fn func(state *AllRateLimits))
+}
+
+type memoryRateLimitStore struct {
+	mu    sync.Mutex
+	state AllRateLimits
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{state: newRateLimitState()}
+}
+
+func (s *memoryRateLimitStore) update(fn func(state *AllRateLimits)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.state)
+}
+
+// fileRateLimitStore shares the state with other running instances through a
+// JSON file guarded by a lock file. If the lock cannot be taken it carries on
+// with the state it last read.
+type fileRateLimitStore struct {
+	path     string
+	lockPath string
+	mu       sync.Mutex
+	state    AllRateLimits
+}
+
+func newFileRateLimitStore(path, lockPath string) *fileRateLimitStore {
+	return &fileRateLimitStore{path: path, lockPath: lockPath, state: newRateLimitState()}
+}
+
+func (s *fileRateLimitStore) update(fn func(state *AllRateLimits)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, err := os.OpenFile(s.lockPath, os.O_CREATE|os.O_RDWR, 0666)
+	if err == nil {
+		if err = lockExclusive(lock); err != nil {
+			lock.Close()
+		}
+	}
+	if err != nil {
+		fn(&s.state)
+		return
+	}
+	defer func() {
+		unlockExclusive(lock)
+		lock.Close()
+	}()
+
+	s.load()
+	fn(&s.state)
+	s.save()
+}
+
+func (s *fileRateLimitStore) load() {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	var loaded AllRateLimits
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return
+	}
+	if loaded.Sxcu.Buckets == nil {
+		loaded.Sxcu.Buckets = make(map[string]*RateLimitEntry)
+	}
+	s.state = loaded
+}
+
+func (s *fileRateLimitStore) save() {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return
+	}
+	os.WriteFile(s.path, data, 0644)
+}
+
+// rateLimitTracker applies the sxcu and imgchest rules to a store, taking the
+// time from now so tests can control it.
+type rateLimitTracker struct {
+	store rateLimitStore
+	now   func() time.Time
+}
+
+var rateLimits = &rateLimitTracker{
+	store: newFileRateLimitStore(getRateLimitFilePath(), getRateLimitLockPath()),
+	now:   time.Now,
+}
+
+// update drops expired entries before handing the state to fn.
+func (t *rateLimitTracker) update(fn func(state *AllRateLimits, nowMs int64)) {
+	nowMs := t.now().UnixMilli()
+	t.store.update(func(state *AllRateLimits) {
+		state.cleanupExpiredEntries(nowMs)
+		fn(state, nowMs)
+	})
+}
+
+// sxcu returns the limiter for an sxcu route bucket; the empty bucket only
+// tracks the global limit.
+func (t *rateLimitTracker) sxcu(routeBucket string) rateLimiter {
+	return sxcuLimiter{tracker: t, route: routeBucket}
+}
+
+func (t *rateLimitTracker) imgchest() rateLimiter {
+	return imgchestLimiter{tracker: t}
+}
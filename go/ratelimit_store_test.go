@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time { return c.t }
+
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestTracker(store rateLimitStore) (*rateLimitTracker, *testClock) {
+	clock := &testClock{t: time.UnixMilli(1_700_000_000_000)}
+	return &rateLimitTracker{store: store, now: clock.now}, clock
+}
+
+func TestSxcuBucketLimit(t *testing.T) {
+	tracker, clock := newTestTracker(newMemoryRateLimitStore())
+	limiter := tracker.sxcu(sxcuFileUploadBucket)
+
+	limiter.update(RateLimitHeaders{Limit: 5, Remaining: 1, ResetAfter: 10}, false, false)
+	if check := limiter.check(); !check.Allowed {
+		t.Fatalf("one request left: %+v", check)
+	}
+
+	limiter.update(RateLimitHeaders{Limit: -1, Remaining: -1}, false, false)
+	check := limiter.check()
+	if check.Allowed || check.Reason != "bucket" || check.Bucket != sxcuFileUploadBucket || check.WaitMs != 10100 {
+		t.Fatalf("bucket spent: %+v", check)
+	}
+	if other := tracker.sxcu(sxcuCollectionBucket).check(); !other.Allowed {
+		t.Errorf("other bucket blocked: %+v", other)
+	}
+
+	clock.advance(4 * time.Second)
+	if check := limiter.check(); check.Allowed || check.WaitMs != 6100 {
+		t.Errorf("after 4s: %+v", check)
+	}
+	clock.advance(6 * time.Second)
+	if check := limiter.check(); !check.Allowed {
+		t.Errorf("after reset: %+v", check)
+	}
+}
+
+func TestSxcuRateLimitErrorResetsBucket(t *testing.T) {
+	tracker, _ := newTestTracker(newMemoryRateLimitStore())
+	limiter := tracker.sxcu(sxcuLinkBucket)
+
+	limiter.update(RateLimitHeaders{Limit: 5, Remaining: 4, ResetAfter: 60}, false, false)
+	limiter.update(RateLimitHeaders{Limit: -1, Remaining: -1, ResetAfter: 2}, false, true)
+	if check := limiter.check(); check.Allowed || check.WaitMs != 2100 {
+		t.Errorf("after rate-limit error: %+v", check)
+	}
+}
+
+func TestSxcuGlobalLimit(t *testing.T) {
+	tracker, clock := newTestTracker(newMemoryRateLimitStore())
+	limiter := tracker.sxcu(sxcuFileUploadBucket)
+	none := RateLimitHeaders{Limit: -1, Remaining: -1}
+
+	for i := 0; i < sxcuGlobalRequestsPerMinute-1; i++ {
+		limiter.update(none, false, false)
+	}
+	if check := limiter.check(); !check.Allowed {
+		t.Fatalf("before the global limit: %+v", check)
+	}
+	limiter.update(none, false, false)
+	check := tracker.sxcu(sxcuLinkBucket).check()
+	if check.Allowed || check.Reason != "global" || check.Bucket != sxcuGlobalBucket {
+		t.Fatalf("global limit spent: %+v", check)
+	}
+
+	clock.advance(sxcuGlobalWindowMs * time.Millisecond)
+	if check := limiter.check(); !check.Allowed {
+		t.Fatalf("after the window: %+v", check)
+	}
+
+	tracker.sxcu("").update(RateLimitHeaders{Limit: -1, Remaining: -1, ResetAfter: 3}, true, true)
+	if check := limiter.check(); check.Allowed || check.Reason != "global" || check.WaitMs != 3100 {
+		t.Errorf("after a global 429: %+v", check)
+	}
+}
+
+func TestImgchestLimit(t *testing.T) {
+	tracker, clock := newTestTracker(newMemoryRateLimitStore())
+	limiter := tracker.imgchest()
+
+	limiter.update(RateLimitHeaders{Limit: 60, Remaining: 1}, false, false)
+	if check := limiter.check(); !check.Allowed {
+		t.Fatalf("one request left: %+v", check)
+	}
+	limiter.update(RateLimitHeaders{Limit: -1, Remaining: -1}, false, false)
+	if check := limiter.check(); check.Allowed || check.WaitMs != imgchestWindowMs+100 {
+		t.Fatalf("limit spent: %+v", check)
+	}
+
+	clock.advance(imgchestWindowMs * time.Millisecond)
+	if check := limiter.check(); !check.Allowed {
+		t.Errorf("after the window: %+v", check)
+	}
+}
+
+func TestFileRateLimitStoreShared(t *testing.T) {
+	dir := t.TempDir()
+	path, lockPath := filepath.Join(dir, "rate_limits.json"), filepath.Join(dir, "rate_limits.lock")
+
+	first, clock := newTestTracker(newFileRateLimitStore(path, lockPath))
+	second := &rateLimitTracker{store: newFileRateLimitStore(path, lockPath), now: clock.now}
+
+	first.imgchest().update(RateLimitHeaders{Limit: 60, Remaining: 0}, false, false)
+	if check := second.imgchest().check(); check.Allowed {
+		t.Fatalf("second store did not see the first's state: %+v", check)
+	}
+
+	clock.advance(imgchestWindowMs * time.Millisecond)
+	if check := second.imgchest().check(); !check.Allowed {
+		t.Errorf("expired entry still blocks: %+v", check)
+	}
+	first.update(func(state *AllRateLimits, _ int64) {
+		if state.Imgchest.Default != nil {
+			t.Errorf("expired entry was not cleaned up: %+v", state.Imgchest.Default)
+		}
+	})
+}
+
+func TestExecuteRateLimitedWaitsForReset(t *testing.T) {
+	tracker, clock := newTestTracker(newMemoryRateLimitStore())
+	limited := &http.Response{
+		StatusCode: 429,
+		Header: http.Header{
+			"X-Ratelimit-Bucket":      {"uploads"},
+			"X-Ratelimit-Limit":       {"5"},
+			"X-Ratelimit-Remaining":   {"0"},
+			"X-Ratelimit-Reset-After": {"7"},
+		},
+		Body: testResponse(429, "").Body,
+	}
+	responses := []*http.Response{limited, testResponse(200, "")}
+
+	var waited []int64
+	attempts, err := executeRateLimited(context.Background(), rateLimitedRequest{
+		Limiter:    tracker.sxcu(sxcuFileUploadBucket),
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: 2,
+		Send: func() (*http.Response, error) {
+			resp := responses[0]
+			responses = responses[1:]
+			return resp, nil
+		},
+		Classify: func(resp *http.Response, headers RateLimitHeaders) rateLimitOutcome {
+			return sxcuOutcome(resp, headers, "slow down", 0)
+		},
+		OnWait: func(waitMs int64, bucket string) {
+			waited = append(waited, waitMs)
+			clock.advance(time.Duration(waitMs) * time.Millisecond)
+		},
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("attempts = %d, err = %v", attempts, err)
+	}
+	if len(waited) != 1 || waited[0] != 7100 {
+		t.Errorf("waited = %v, want [7100]", waited)
+	}
+}
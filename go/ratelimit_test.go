@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeLimiter struct {
+	checks  []RateLimitCheckResult
+	updates []bool
+}
+
+func (l *fakeLimiter) bucket() string { return "fake" }
+
+func (l *fakeLimiter) check() RateLimitCheckResult {
+	if len(l.checks) == 0 {
+		return RateLimitCheckResult{Allowed: true}
+	}
+	check := l.checks[0]
+	l.checks = l.checks[1:]
+	return check
+}
+
+func (l *fakeLimiter) update(_ RateLimitHeaders, _, isRateLimitError bool) {
+	l.updates = append(l.updates, isRateLimitError)
+}
+
+func classifyStatus(resp *http.Response, _ RateLimitHeaders) rateLimitOutcome {
+	if resp.StatusCode == 429 {
+		return rateLimitOutcome{Limited: true, Err: errors.New("rate limit exceeded")}
+	}
+	return rateLimitOutcome{}
+}
+
+func TestExecuteRateLimitedRetriesAfterWait(t *testing.T) {
+	limiter := &fakeLimiter{checks: []RateLimitCheckResult{
+		{Allowed: false, WaitMs: 500, Bucket: "__sxcu_global__"},
+		{Allowed: true},
+		{Allowed: false, WaitMs: 1500},
+	}}
+	statuses := []int{429, 200}
+	var waits []string
+	attempts, err := executeRateLimited(context.Background(), rateLimitedRequest{
+		Limiter:    limiter,
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: 3,
+		Send: func() (*http.Response, error) {
+			status := statuses[0]
+			statuses = statuses[1:]
+			return testResponse(status, ""), nil
+		},
+		Classify: classifyStatus,
+		OnWait: func(waitMs int64, bucket string) {
+			waits = append(waits, fmt.Sprintf("%s:%d", bucket, waitMs))
+		},
+	})
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+	if want := "__sxcu_global__:500 fake:1500"; strings.Join(waits, " ") != want {
+		t.Errorf("waits = %q, want %q", strings.Join(waits, " "), want)
+	}
+	if len(limiter.updates) != 2 || !limiter.updates[0] || limiter.updates[1] {
+		t.Errorf("updates = %v, want [true false]", limiter.updates)
+	}
+}
+
+func TestExecuteRateLimitedFailFast(t *testing.T) {
+	sent := 0
+	send := func() (*http.Response, error) {
+		sent++
+		return testResponse(429, ""), nil
+	}
+
+	limiter := &fakeLimiter{checks: []RateLimitCheckResult{{Allowed: false, WaitMs: 2000}}}
+	attempts, err := executeRateLimited(context.Background(), rateLimitedRequest{
+		Limiter: limiter, Policy: failFastRateLimitPolicy, MaxRetries: 3, Send: send, Classify: classifyStatus,
+	})
+	if err == nil || err.Error() != "rate limit exceeded, retry after 2000ms" || attempts != 1 || sent != 0 {
+		t.Errorf("blocked: attempts = %d, sent = %d, err = %v", attempts, sent, err)
+	}
+
+	attempts, err = executeRateLimited(context.Background(), rateLimitedRequest{
+		Limiter: &fakeLimiter{}, Policy: failFastRateLimitPolicy, MaxRetries: 3, Send: send, Classify: classifyStatus,
+	})
+	if err == nil || err.Error() != "rate limit exceeded" || attempts != 1 || sent != 1 {
+		t.Errorf("429: attempts = %d, sent = %d, err = %v", attempts, sent, err)
+	}
+}
+
+func TestExecuteRateLimitedSendErrors(t *testing.T) {
+	fatal := errors.New("failed to create request")
+	attempts, err := executeRateLimited(context.Background(), rateLimitedRequest{
+		Limiter:    &fakeLimiter{},
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: 3,
+		Send:       func() (*http.Response, error) { return nil, fatal },
+		Classify:   classifyStatus,
+	})
+	if !errors.Is(err, fatal) || attempts != 1 {
+		t.Errorf("fatal: attempts = %d, err = %v", attempts, err)
+	}
+
+	dropped := errors.New("connection reset")
+	attempts, err = executeRateLimited(context.Background(), rateLimitedRequest{
+		Limiter:    &fakeLimiter{},
+		Policy:     retryAfterWaitRateLimitPolicy,
+		MaxRetries: 0,
+		Send:       func() (*http.Response, error) { return nil, retryable(dropped) },
+		Classify:   classifyStatus,
+	})
+	if !errors.Is(err, dropped) || attempts != 1 {
+		t.Errorf("retryable: attempts = %d, err = %v", attempts, err)
+	}
+}
+
+func TestSendRequestRetriesTransportErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("file"))
+	if _, err := sendRequest(req); !isRetryable(err) {
+		t.Errorf("POST with lost reply: error = %v, want retryable", err)
+	}
+}
+
+func TestExecuteRateLimitedSendAfterWait(t *testing.T) {
+	limiter := &fakeLimiter{checks: []RateLimitCheckResult{{Allowed: false, WaitMs: 800}}}
+	sent := 0
+	var waits []int64
+	attempts, err := executeRateLimited(context.Background(), rateLimitedRequest{
+		Limiter:    limiter,
+		Policy:     sendAfterWaitRateLimitPolicy,
+		MaxRetries: 1,
+		Send: func() (*http.Response, error) {
+			sent++
+			return testResponse(200, ""), nil
+		},
+		Classify: classifyStatus,
+		OnWait:   func(waitMs int64, _ string) { waits = append(waits, waitMs) },
+	})
+	if err != nil || attempts != 1 || sent != 1 {
+		t.Errorf("attempts = %d, sent = %d, err = %v", attempts, sent, err)
+	}
+	if len(waits) != 1 || waits[0] != 800 {
+		t.Errorf("waits = %v, want [800]", waits)
+	}
+}
+
+func TestSxcuOutcome(t *testing.T) {
+	tests := []struct {
+		status  int
+		global  bool
+		code    int
+		limited bool
+		isGlob  bool
+	}{
+		{200, false, 0, false, false},
+		{200, false, 815, true, false},
+		{429, false, 0, true, false},
+		{429, true, 0, true, true},
+		{429, false, 2, true, true},
+		{400, false, 2, false, false},
+	}
+	for _, tt := range tests {
+		got := sxcuOutcome(testResponse(tt.status, ""), RateLimitHeaders{IsGlobal: tt.global}, "slow down", tt.code, 815, 185)
+		if got.Limited != tt.limited || got.Global != tt.isGlob {
+			t.Errorf("%d global=%v code=%d: limited=%v global=%v, want %v %v", tt.status, tt.global, tt.code, got.Limited, got.Global, tt.limited, tt.isGlob)
+		}
+		if got.Limited && got.Err == nil {
+			t.Errorf("%d code=%d: limited without an error", tt.status, tt.code)
+		}
+	}
+}
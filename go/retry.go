@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+)
+
+// catbox and kek do not publish rate limits, so their requests only retry
+// failures another attempt may fix.
+const (
+	catboxMaxRetries = 3
+	kekMaxRetries    = 3
+)
+
+// retryableError marks a failure that is worth another attempt, such as a
+// dropped connection or a 5xx response.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+func isRetryable(err error) bool {
+	var r *retryableError
+	return errors.As(err, &r)
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// maybeDeliveredError marks a failed upload the server may have acted on
+// anyway: the connection dropped or a 5xx came back after the body was sent.
+// Repeating it can leave a duplicate behind.
+type maybeDeliveredError struct {
+	err error
+}
+
+func (e *maybeDeliveredError) Error() string { return e.err.Error() }
+func (e *maybeDeliveredError) Unwrap() error { return e.err }
+
+func maybeDelivered(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &maybeDeliveredError{err: err}
+}
+
+func isMaybeDelivered(err error) bool {
+	var m *maybeDeliveredError
+	return errors.As(err, &m)
+}
+
+// uploadStatusError classifies err, the failure of an upload that got the
+// status code back. A 408 or 429 was refused unread and can simply be sent
+// again, while after a 5xx the upload may exist.
+func uploadStatusError(status int, err error) error {
+	switch {
+	case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests:
+		return retryable(err)
+	case status >= 500:
+		return maybeDelivered(err)
+	}
+	return err
+}
+
+// withRetry calls attempt until it succeeds, returns an error not marked
+// retryable, or maxRetries retries have been spent, backing off in between.
+func withRetry(ctx context.Context, maxRetries int, attempt func() error) error {
+	for i := 0; ; i++ {
+		err := attempt()
+		if err == nil || !isRetryable(err) || i >= maxRetries || ctx.Err() != nil {
+			return err
+		}
+		if err := sleepContext(ctx, calculateExponentialBackoff(i, 1000, 30000)); err != nil {
+			return err
+		}
+	}
+}
+
+// withUploadRetry is withRetry for uploads, which create something new every
+// time they are sent. Failures marked retryable never reached the server and
+// are repeated as they are. Before a failure marked maybeDelivered is
+// repeated, found is asked whether the failed attempt created the upload
+// after all; if it did, found keeps that result and nothing is sent again.
+// found is nil where the provider offers no way to look, and the upload is
+// then repeated, accepting a possible duplicate over losing the file.
+func withUploadRetry(ctx context.Context, maxRetries int, attempt func() error, found func() bool) error {
+	for i := 0; ; i++ {
+		err := attempt()
+		if err == nil || i >= maxRetries || ctx.Err() != nil {
+			return err
+		}
+		if !isRetryable(err) {
+			if !isMaybeDelivered(err) {
+				return err
+			}
+			if found != nil && found() {
+				return nil
+			}
+		}
+		if err := sleepContext(ctx, calculateExponentialBackoff(i, 1000, 30000)); err != nil {
+			return err
+		}
+	}
+}
+
+// sendOnce sends an upload, which creates a new file, album or post every
+// time it is sent, and tells apart failures by whether the server may have
+// seen it. Those that provably did not get through are marked retryable: the
+// host could not be reached, or a gateway answered 502, 503 or 504 before the
+// body was written. Any other transport failure is marked maybeDelivered.
+// Every other response is returned as is.
+func sendOnce(req *http.Request) (*http.Response, error) {
+	var wrote atomic.Bool
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				wrote.Store(true)
+			}
+		},
+	}))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("request failed: %w", err)
+		if isUndelivered(err) {
+			return nil, retryable(err)
+		}
+		return nil, maybeDelivered(err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if wrote.Load() {
+			break
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, retryable(fmt.Errorf("API error: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+	return resp, nil
+}
+
+// isUndelivered reports whether err shows the request never reached the
+// server because its host could not be resolved or connected to.
+func isUndelivered(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testResponse(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestReadCatboxResult(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		want      string
+		retryable bool
+		delivered bool
+	}{
+		{200, "https://files.catbox.moe/abc.png\n", "https://files.catbox.moe/abc.png", false, false},
+		{200, "File type not allowed", "", false, false},
+		{412, "No files given", "", false, false},
+		{200, "", "", false, true},
+		{502, "<html>Bad Gateway</html>", "", false, true},
+		{429, "slow down", "", true, false},
+	}
+	for _, tt := range tests {
+		got, err := readCatboxResult(testResponse(tt.status, tt.body))
+		if got != tt.want {
+			t.Errorf("%d %q: result = %q, want %q", tt.status, tt.body, got, tt.want)
+		}
+		if tt.want == "" && err == nil {
+			t.Errorf("%d %q: no error", tt.status, tt.body)
+		}
+		if isRetryable(err) != tt.retryable || isMaybeDelivered(err) != tt.delivered {
+			t.Errorf("%d %q: retryable = %v, maybe delivered = %v, want %v, %v (%v)", tt.status, tt.body, isRetryable(err), isMaybeDelivered(err), tt.retryable, tt.delivered, err)
+		}
+	}
+}
+
+func TestWithUploadRetryChecksBeforeRepeating(t *testing.T) {
+	attempts, checks := 0, 0
+	err := withUploadRetry(context.Background(), 3, func() error {
+		attempts++
+		return maybeDelivered(errors.New("connection reset"))
+	}, func() bool {
+		checks++
+		return true
+	})
+	if err != nil || attempts != 1 || checks != 1 {
+		t.Errorf("found: err = %v after %d attempts and %d checks, want nil after 1 and 1", err, attempts, checks)
+	}
+
+	fatal := errors.New("file type not allowed")
+	attempts, checks = 0, 0
+	err = withUploadRetry(context.Background(), 3, func() error {
+		attempts++
+		return fatal
+	}, func() bool {
+		checks++
+		return false
+	})
+	if err != fatal || attempts != 1 || checks != 0 {
+		t.Errorf("fatal: err = %v after %d attempts and %d checks", err, attempts, checks)
+	}
+}
+
+func TestReadKekResponse(t *testing.T) {
+	if _, err := readKekResponse(testResponse(503, "maintenance")); isRetryable(err) {
+		t.Errorf("503: error %v is retryable", err)
+	}
+	_, err := readKekResponse(testResponse(403, `{"error":"Upgrade your plan"}`))
+	var apiErr *KekAPIError
+	if isRetryable(err) || !errors.As(err, &apiErr) || !apiErr.IsPlanRefusal() {
+		t.Errorf("403: error = %v, want a fatal plan refusal", err)
+	}
+}
+
+func TestSendOnce(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// The whole body reached the server, so it may have acted on it.
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("file"))
+	resp, err := sendOnce(req)
+	if err != nil {
+		t.Fatalf("delivered: error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("delivered: status = %d", resp.StatusCode)
+	}
+
+	// A gateway that answers before reading any of the body.
+	gateway, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+	go func() {
+		conn, err := gateway.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil || line == "\r\n" {
+				break
+			}
+		}
+		io.WriteString(conn, "HTTP/1.1 503 Service Unavailable\r\nContent-Length: 0\r\n\r\n")
+		io.Copy(io.Discard, br)
+	}()
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	req, _ = http.NewRequest("POST", "http://"+gateway.Addr().String(), pr)
+	if _, err := sendOnce(req); !isRetryable(err) {
+		t.Errorf("early 503: error = %v, want retryable", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	req, _ = http.NewRequest("POST", "http://"+addr, strings.NewReader("file"))
+	if _, err := sendOnce(req); !isRetryable(err) {
+		t.Errorf("refused connection: error = %v, want retryable", err)
+	}
+}
+
+func TestWithRetryStopsOnFatalError(t *testing.T) {
+	fatal := errors.New("file type not allowed")
+	attempts := 0
+	err := withRetry(context.Background(), 3, func() error {
+		attempts++
+		return fatal
+	})
+	if err != fatal || attempts != 1 {
+		t.Fatalf("withRetry() = %v after %d attempts, want %v after 1", err, attempts, fatal)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	err = withRetry(ctx, 3, func() error {
+		attempts++
+		return retryable(errors.New("connection reset"))
+	})
+	if !isRetryable(err) || attempts != 1 {
+		t.Fatalf("withRetry() = %v after %d attempts, want the retryable error after 1", err, attempts)
+	}
+}
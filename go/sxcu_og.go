@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sxcuOGOmit disables a tag instead of leaving it to the sxcu default.
+const sxcuOGOmit = "false"
+
+var sxcuOGColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// SxcuOGProperties controls how sxcu links embed in Discord and other chat
+// tools. Empty fields keep the sxcu default; "false" omits the tag.
+type SxcuOGProperties struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Color       string `json:"color,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+	ShowURL     bool   `json:"show_url,omitempty"`
+}
+
+func (o SxcuOGProperties) IsZero() bool {
+	return o == SxcuOGProperties{}
+}
+
+// Validate checks the color format. Lengths are left to sxcu, which rejects
+// oversized properties with error 807.
+func (o SxcuOGProperties) Validate() error {
+	if o.Color != "" && o.Color != sxcuOGOmit && !sxcuOGColorPattern.MatchString(o.Color) {
+		return fmt.Errorf("invalid embed color %q (expected a hex color such as #7289DA)", o.Color)
+	}
+	return nil
+}
+
+// encode returns the og_properties form value, or "" when nothing is set.
+func (o SxcuOGProperties) encode() (string, error) {
+	if o.IsZero() {
+		return "", nil
+	}
+	props := make(map[string]interface{})
+	set := func(key, value string) {
+		switch value {
+		case "":
+		case sxcuOGOmit:
+			props[key] = false
+		default:
+			props[key] = value
+		}
+	}
+	set("title", o.Title)
+	set("description", o.Description)
+	set("color", o.Color)
+	set("site_name", o.SiteName)
+	if o.ShowURL {
+		props["discord_hide_url"] = false
+	}
+	data, err := json.Marshal(props)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode embed properties: %w", err)
+	}
+	return string(data), nil
+}
+
+// Merge returns o with every field that is set in override replaced.
+func (o SxcuOGProperties) Merge(override SxcuOGProperties) SxcuOGProperties {
+	if override.Title != "" {
+		o.Title = override.Title
+	}
+	if override.Description != "" {
+		o.Description = override.Description
+	}
+	if override.Color != "" {
+		o.Color = override.Color
+	}
+	if override.SiteName != "" {
+		o.SiteName = override.SiteName
+	}
+	o.ShowURL = o.ShowURL || override.ShowURL
+	return o
+}
+
+type OGPresetStore struct {
+	path string
+}
+
+func NewOGPresetStore(path string) *OGPresetStore {
+	return &OGPresetStore{path: path}
+}
+
+func OpenOGPresetStore() (*OGPresetStore, error) {
+	if p := os.Getenv("IMAGE_UPLOADER_OG_PRESETS"); p != "" {
+		return NewOGPresetStore(p), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return NewOGPresetStore(filepath.Join(dir, "image-uploader", "sxcu-embed-presets.json")), nil
+}
+
+func (s *OGPresetStore) Load() (map[string]SxcuOGProperties, error) {
+	presets := make(map[string]SxcuOGProperties)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return presets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embed presets: %w", err)
+	}
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("failed to parse embed presets: %w", err)
+	}
+	return presets, nil
+}
+
+func (s *OGPresetStore) Names() ([]string, error) {
+	presets, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *OGPresetStore) Get(name string) (SxcuOGProperties, error) {
+	presets, err := s.Load()
+	if err != nil {
+		return SxcuOGProperties{}, err
+	}
+	props, ok := presets[strings.TrimSpace(name)]
+	if !ok {
+		return SxcuOGProperties{}, fmt.Errorf("unknown embed preset %q", name)
+	}
+	return props, nil
+}
+
+func (s *OGPresetStore) Save(name string, props SxcuOGProperties) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("preset name is empty")
+	}
+	if err := props.Validate(); err != nil {
+		return err
+	}
+	presets, err := s.Load()
+	if err != nil {
+		return err
+	}
+	presets[name] = props
+	return s.write(presets)
+}
+
+func (s *OGPresetStore) Delete(name string) error {
+	presets, err := s.Load()
+	if err != nil {
+		return err
+	}
+	delete(presets, strings.TrimSpace(name))
+	return s.write(presets)
+}
+
+func (s *OGPresetStore) write(presets map[string]SxcuOGProperties) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode embed presets: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write embed presets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSxcuOGPropertiesEncode(t *testing.T) {
+	got, err := SxcuOGProperties{Title: "Screenshot", Color: "#7289DA", SiteName: "false", ShowURL: true}.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"color":"#7289DA","discord_hide_url":false,"site_name":false,"title":"Screenshot"}`
+	if got != want {
+		t.Fatalf("encode() = %s, want %s", got, want)
+	}
+	if got, _ := (SxcuOGProperties{}).encode(); got != "" {
+		t.Fatalf("empty encode() = %q, want empty", got)
+	}
+}
+
+func TestSxcuOGPropertiesValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		props SxcuOGProperties
+		want  string
+	}{
+		{"bad color", SxcuOGProperties{Color: "7289DA"}, "invalid embed color"},
+	}
+	for _, tt := range tests {
+		err := tt.props.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Validate() = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if err := (SxcuOGProperties{Title: strings.Repeat("a", 5000), Color: "#fff", Description: "false"}).Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestOGPresetStore(t *testing.T) {
+	store := NewOGPresetStore(filepath.Join(t.TempDir(), "presets.json"))
+	discord := SxcuOGProperties{Title: "Team shots", Color: "#ff0000"}
+	if err := store.Save("discord", discord); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("plain", SxcuOGProperties{SiteName: "false"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("bad", SxcuOGProperties{Color: "red"}); err == nil {
+		t.Error("saved a preset with an invalid color")
+	}
+
+	names, err := store.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"discord", "plain"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Names() = %v, want %v", names, want)
+	}
+
+	got, err := store.Get("discord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := got.Merge(SxcuOGProperties{Title: "Override", ShowURL: true})
+	if merged.Title != "Override" || merged.Color != "#ff0000" || !merged.ShowURL {
+		t.Fatalf("Merge() = %+v", merged)
+	}
+
+	if err := store.Delete("discord"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("discord"); err == nil {
+		t.Error("deleted preset still found")
+	}
+}
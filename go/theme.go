@@ -1,3 +1,5 @@
+//go:build windows
+
 package main
 
 import (
@@ -85,6 +87,12 @@ func ApplyDarkTheme(a *App) {
 	applyDarkToLineEdit(a.postIDEdit)
 	applyDarkToLineEdit(a.imgchestTokenEdit)
 	applyDarkToLineEdit(a.kekApiKeyEdit)
+	applyDarkToLineEdit(a.catboxUserhashEdit)
+	applyDarkToLineEdit(a.albumShortEdit)
+	applyDarkToLineEdit(a.sxcuSubdomainEdit)
+	applyDarkToLineEdit(a.sxcuUploadTokenEdit)
+	applyDarkToLineEdit(a.sxcuCollectionEdit)
+	applyDarkToLineEdit(a.sxcuCollTokenEdit)
 
 	applyDarkToTextEdit(a.outputEdit)
 	applyDarkToListBox(a.fileListBox)
@@ -92,13 +100,26 @@ func ApplyDarkTheme(a *App) {
 	applyDarkToCheckBox(a.albumCheck)
 	applyDarkToCheckBox(a.collectionCheck)
 	applyDarkToCheckBox(a.sxcuPrivateCheck)
+	applyDarkToCheckBox(a.selfDestructCheck)
+	applyDarkToCheckBox(a.sxcuPasteCheck)
 	applyDarkToCheckBox(a.anonymousCheck)
 	applyDarkToCheckBox(a.nsfwCheck)
 	applyDarkToCheckBox(a.kekMatureCheck)
 	applyDarkToComboBox(a.privacyCombo)
+	applyDarkToComboBox(a.expiryCombo)
+	applyDarkToComboBox(a.kekVisibilityCombo)
 
 	applyDarkToButton(a.uploadButton)
+	applyDarkToButton(a.cancelButton)
 	applyDarkToButton(a.copyButton)
+	applyDarkToButton(a.shortenButton)
+	applyDarkToButton(a.historyButton)
+	applyDarkToButton(a.manageAlbumButton)
+	applyDarkToButton(a.sxcuCheckButton)
+	applyDarkToButton(a.sxcuEmbedButton)
+	applyDarkToButton(a.viewPostButton)
+	applyDarkToButton(a.browsePostsButton)
+	applyDarkToButton(a.kekPostsButton)
 
 	applyDarkToLabels(a.mainWindow)
 	subclassComposites(a)
@@ -0,0 +1,883 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var timeNow = time.Now
+
+type catboxProvider struct{}
+
+func (catboxProvider) Name() string { return "catbox" }
+
+func (catboxProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{
+		URLUpload:   true,
+		GroupLabel:  "Album",
+		Title:       true,
+		Description: true,
+		MaxFileSize: catboxMaxFileSize,
+	}
+}
+
+func (catboxProvider) UploadFile(ctx context.Context, job *UploadJob, filePath string, group *UploadGroup) (*UploadResult, error) {
+	if err := ValidateCatboxFile(filePath, job.LitterboxExpiry); err != nil {
+		return nil, err
+	}
+	if job.LitterboxExpiry != "" {
+		url, err := uploadFileToLitterbox(ctx, filePath, job.LitterboxExpiry)
+		if err != nil {
+			return nil, err
+		}
+		return &UploadResult{URL: url, ID: extractCatboxFilename(url), ExpiresAt: timeNow().Add(litterboxExpiries[job.LitterboxExpiry])}, nil
+	}
+	url, err := uploadFileToCatbox(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{URL: url, ID: extractCatboxFilename(url)}, nil
+}
+
+func (catboxProvider) UploadURL(ctx context.Context, job *UploadJob, targetURL string) (*UploadResult, error) {
+	if job.LitterboxExpiry != "" {
+		return nil, fmt.Errorf("Litterbox URL uploads: %w", errUnsupported)
+	}
+	url, err := uploadURLToCatbox(ctx, targetURL)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{URL: url, ID: extractCatboxFilename(url)}, nil
+}
+
+func (catboxProvider) CreateGroup(ctx context.Context, job *UploadJob, items []*UploadResult) (*UploadGroup, error) {
+	fileNames := make([]string, 0, len(items))
+	for _, item := range items {
+		fileNames = append(fileNames, item.ID)
+	}
+	albumURL, err := createCatboxAlbum(ctx, fileNames, job.Title, job.Desc)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadGroup{ID: catboxAlbumShort(albumURL), URL: albumURL}, nil
+}
+
+// DeleteUploads deletes the catbox files in one request. Litterbox entries are
+// skipped with an error of their own, since they expire instead.
+func (catboxProvider) DeleteUploads(ctx context.Context, entries []HistoryEntry) ([]HistoryEntry, error) {
+	var skipped []error
+	deletable := make([]HistoryEntry, 0, len(entries))
+	fileNames := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if isLitterboxURL(e.URL) {
+			skipped = append(skipped, fmt.Errorf("%s: Litterbox files cannot be deleted; they expire on their own", e.Name()))
+			continue
+		}
+		deletable = append(deletable, e)
+		fileNames = append(fileNames, firstNonEmpty(e.ItemID, extractCatboxFilename(e.URL)))
+	}
+	if len(fileNames) == 0 {
+		return nil, errors.Join(skipped...)
+	}
+	if err := deleteCatboxFiles(ctx, fileNames); err != nil {
+		return nil, errors.Join(append(skipped, err)...)
+	}
+	return deletable, errors.Join(skipped...)
+}
+
+func (p catboxProvider) Upload(ctx context.Context, job *UploadJob, updateOutput func(string)) UploadSummary {
+	totalFiles := len(job.Files)
+	results := make([]string, 0, totalFiles)
+	errors := make([]string, 0, 4)
+	uploaded := make([]*UploadResult, 0, totalFiles)
+	var albumResult string
+	var albumGroup *UploadGroup
+
+	for _, filePath := range job.Files {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := p.UploadFile(ctx, job, filePath, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			errors = append(errors, fmt.Sprintf("%s: %v", filepath.Base(filePath), err))
+		} else {
+			res.Source = filePath
+			results = append(results, res.URL)
+			uploaded = append(uploaded, res)
+		}
+	}
+
+	for _, u := range job.URLs {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := p.UploadURL(ctx, job, u)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			errors = append(errors, fmt.Sprintf("URL %s: %v", u, err))
+		} else {
+			res.Source = u
+			results = append(results, res.URL)
+			uploaded = append(uploaded, res)
+		}
+	}
+
+	canGroup := job.LitterboxExpiry == "" && len(uploaded) > 0 && ctx.Err() == nil
+	if canGroup && job.AlbumShort != "" {
+		fileNames := make([]string, 0, len(uploaded))
+		for _, item := range uploaded {
+			fileNames = append(fileNames, item.ID)
+		}
+		albumURL, err := addToCatboxAlbum(ctx, job.AlbumShort, fileNames)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Adding to album: %v", err))
+		} else {
+			albumResult = "Album: " + albumURL
+			albumGroup = &UploadGroup{ID: catboxAlbumShort(job.AlbumShort), URL: albumURL}
+		}
+	} else if canGroup && job.CreateAlbum {
+		album, err := p.CreateGroup(ctx, job, uploaded)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Album creation: %v", err))
+		} else {
+			albumResult = "Album: " + album.URL
+			albumGroup = album
+		}
+	}
+
+	return UploadSummary{Results: results, GroupResult: albumResult, Errors: errors, SuccessCount: len(results), Cancelled: ctx.Err() != nil, Items: uploaded, Group: albumGroup}
+}
+
+type kekProvider struct{}
+
+func (kekProvider) Name() string { return "kek" }
+
+func (kekProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{
+		URLUpload:   true,
+		MaxFileSize: 50 * 1024 * 1024,
+	}
+}
+
+func kekUploadResult(resp *KekPostResponse) *UploadResult {
+	postURL := resp.GetURL()
+	if postURL == "" {
+		postURL = resp.GetID()
+	}
+	return &UploadResult{URL: postURL, ID: resp.GetID()}
+}
+
+func (kekProvider) UploadFile(ctx context.Context, job *UploadJob, filePath string, group *UploadGroup) (*UploadResult, error) {
+	apiKey, err := getKekAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := uploadFileToKek(ctx, filePath, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return kekUploadResult(resp), nil
+}
+
+func (kekProvider) UploadURL(ctx context.Context, job *UploadJob, targetURL string) (*UploadResult, error) {
+	apiKey, err := getKekAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := uploadURLToKek(ctx, targetURL, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return kekUploadResult(resp), nil
+}
+
+func (kekProvider) CreateGroup(ctx context.Context, job *UploadJob, items []*UploadResult) (*UploadGroup, error) {
+	return nil, errUnsupported
+}
+
+func (kekProvider) DeleteUploads(ctx context.Context, entries []HistoryEntry) ([]HistoryEntry, error) {
+	apiKey, err := getKekAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	deleted := make([]HistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		id := e.ItemID
+		if id == "" {
+			id = (&KekPostResponse{URL: e.URL}).GetID()
+		}
+		if err := deleteKekPost(ctx, id, apiKey); err != nil {
+			return deleted, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		deleted = append(deleted, e)
+	}
+	return deleted, nil
+}
+
+func (p kekProvider) Upload(ctx context.Context, job *UploadJob, updateOutput func(string)) UploadSummary {
+	apiKey, err := getKekAPIKey()
+	if err != nil {
+		return UploadSummary{Errors: []string{err.Error()}}
+	}
+
+	totalItems := len(job.Files) + len(job.URLs)
+	results := make([]string, 0, totalItems)
+	errors := make([]string, 0, 4)
+	uploaded := make([]*UploadResult, 0, totalItems)
+
+	buildOutput := func() string {
+		var output strings.Builder
+		output.Grow(2048)
+		if len(errors) > 0 {
+			output.WriteString(fmt.Sprintf("Uploading... %d/%d (%d failed)\r\n\r\n", len(results), totalItems, len(errors)))
+		} else {
+			output.WriteString(fmt.Sprintf("Uploading... %d/%d\r\n\r\n", len(results), totalItems))
+		}
+		for _, r := range results {
+			output.WriteString(r)
+			output.WriteString("\r\n")
+		}
+		for _, e := range errors {
+			output.WriteString("Error: ")
+			output.WriteString(e)
+			output.WriteString("\r\n")
+		}
+		return output.String()
+	}
+
+	applyPostOptions := func(label string, res *UploadResult) {
+		if res.ID == "" {
+			errors = append(errors, fmt.Sprintf("%s maturity: missing post ID", label))
+			if job.KekVisibility != "" {
+				errors = append(errors, fmt.Sprintf("%s visibility: missing post ID", label))
+			}
+			return
+		}
+		if err := setKekPostMature(ctx, res.ID, apiKey, job.KekMature); err != nil {
+			errors = append(errors, fmt.Sprintf("%s maturity: %v", label, err))
+		}
+		if job.KekVisibility != "" {
+			if err := setKekPostPublic(ctx, res.ID, apiKey, job.KekVisibility == "public"); err != nil {
+				errors = append(errors, fmt.Sprintf("%s visibility: %v", label, err))
+			}
+		}
+	}
+
+	for _, filePath := range job.Files {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := p.UploadFile(ctx, job, filePath, nil)
+		label := filepath.Base(filePath)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			errors = append(errors, fmt.Sprintf("%s: %v", label, err))
+		} else {
+			res.Source = filePath
+			results = append(results, res.URL)
+			uploaded = append(uploaded, res)
+			applyPostOptions(label, res)
+		}
+		updateOutput(buildOutput())
+	}
+
+	for _, u := range job.URLs {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := p.UploadURL(ctx, job, u)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			errors = append(errors, fmt.Sprintf("URL %s: %v", u, err))
+		} else {
+			res.Source = u
+			results = append(results, res.URL)
+			uploaded = append(uploaded, res)
+			applyPostOptions("URL "+u, res)
+		}
+		updateOutput(buildOutput())
+	}
+
+	return UploadSummary{Results: results, Errors: errors, SuccessCount: len(results), Cancelled: ctx.Err() != nil, Items: uploaded}
+}
+
+type sxcuProvider struct{}
+
+func (sxcuProvider) Name() string { return "sxcu" }
+
+func (sxcuProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{
+		GroupLabel:  "Collection",
+		Title:       true,
+		Description: true,
+		MaxFileSize: 95 * 1024 * 1024,
+	}
+}
+
+func sxcuUploadOptions(job *UploadJob, group *UploadGroup) SxcuUploadOptions {
+	opts := SxcuUploadOptions{OG: job.SxcuOG, SelfDestruct: job.SxcuSelfDestruct}
+	opts.Subdomain, opts.UploadToken = getSxcuSubdomain()
+	if group != nil {
+		opts.CollectionID = group.ID
+		opts.CollectionToken = group.Token
+	}
+	return opts
+}
+
+func (sxcuProvider) UploadFile(ctx context.Context, job *UploadJob, filePath string, group *UploadGroup) (*UploadResult, error) {
+	var resp *SxcuResponse
+	var err error
+	if job.SxcuPaste {
+		resp, err = uploadPasteFile(ctx, filePath, 5)
+	} else {
+		resp, err = uploadFileToSxcu(ctx, filePath, sxcuUploadOptions(job, group), 5)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sxcuUploadResult(job, resp, filePath), nil
+}
+
+func sxcuUploadResult(job *UploadJob, resp *SxcuResponse, filePath string) *UploadResult {
+	res := &UploadResult{URL: resp.URL, ID: resp.ID, Source: filePath, DeleteURL: resp.DelURL, Thumb: resp.Thumb}
+	if job.SxcuSelfDestruct && !job.SxcuPaste {
+		res.ExpiresAt = timeNow().Add(sxcuSelfDestructAfter)
+	}
+	return res
+}
+
+func (sxcuProvider) UploadURL(ctx context.Context, job *UploadJob, targetURL string) (*UploadResult, error) {
+	return nil, errUnsupported
+}
+
+func (sxcuProvider) CreateGroup(ctx context.Context, job *UploadJob, items []*UploadResult) (*UploadGroup, error) {
+	collTitle := job.Title
+	if collTitle == "" {
+		collTitle = "Untitled"
+	}
+	coll, err := createSxcuCollection(ctx, collTitle, job.Desc, job.SxcuCollection, 5)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadGroup{ID: coll.CollectionID, URL: coll.GetURL(), Token: coll.CollectionToken}, nil
+}
+
+func (sxcuProvider) DeleteUploads(ctx context.Context, entries []HistoryEntry) ([]HistoryEntry, error) {
+	deleted := make([]HistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		target := firstNonEmpty(e.DeleteURL, e.URL)
+		id, token, err := parseSxcuDeleteURL(target)
+		if err != nil {
+			return deleted, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		switch {
+		case isPasteURL(target):
+			err = deletePaste(ctx, id, token, 5)
+		case strings.Contains(target, "/api/links/"):
+			err = deleteSxcuLink(ctx, id, token, 5)
+		default:
+			err = deleteSxcuFile(ctx, id, token, 5)
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		deleted = append(deleted, e)
+	}
+	return deleted, nil
+}
+
+func (sxcuProvider) existingCollection(ctx context.Context, job *UploadJob) (*UploadGroup, error) {
+	id := sxcuCollectionID(job.SxcuCollectionID)
+	token := job.SxcuCollectionToken
+	if token == "" {
+		token = rememberedGroupToken("sxcu", id)
+	}
+	info, err := getSxcuCollection(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Public && token == "" {
+		return nil, fmt.Errorf("collection %s is private; enter its collection token", id)
+	}
+	return &UploadGroup{ID: id, URL: "https://sxcu.net/c/" + id, Token: token}, nil
+}
+
+func (p sxcuProvider) Upload(ctx context.Context, job *UploadJob, updateOutput func(string)) UploadSummary {
+	totalFiles := len(job.Files)
+	results := make([]string, 0, totalFiles)
+	errors := make([]string, 0, 4)
+	uploaded := make([]*UploadResult, 0, totalFiles)
+	var collectionResult string
+	var collection UploadGroup
+	var rateLimitStatus string
+
+	buildOutput := func() string {
+		var output strings.Builder
+		output.Grow(2048)
+		successCount := len(results)
+		failCount := len(errors)
+		if failCount > 0 {
+			output.WriteString(fmt.Sprintf("Uploading... %d/%d (%d failed)\r\n\r\n", successCount, totalFiles, failCount))
+		} else {
+			output.WriteString(fmt.Sprintf("Uploading... %d/%d\r\n\r\n", successCount, totalFiles))
+		}
+		if rateLimitStatus != "" {
+			output.WriteString(rateLimitStatus)
+			output.WriteString("\r\n")
+		}
+		if collectionResult != "" {
+			output.WriteString(collectionResult)
+			output.WriteString("\r\n")
+		}
+		for _, r := range results {
+			output.WriteString(r)
+			output.WriteString("\r\n")
+		}
+		for _, e := range errors {
+			output.WriteString("Error: ")
+			output.WriteString(e)
+			output.WriteString("\r\n")
+		}
+		return output.String()
+	}
+
+	waitWithCountdown := func(waitMs int64, bucket string) {
+		friendlyBucket := bucket
+		switch bucket {
+		case sxcuFileUploadBucket:
+			friendlyBucket = "file upload"
+		case sxcuCollectionBucket:
+			friendlyBucket = "collection"
+		case sxcuFileDeleteBucket:
+			friendlyBucket = "file delete"
+		case sxcuLinkBucket:
+			friendlyBucket = "link"
+		case sxcuLinkDeleteBucket:
+			friendlyBucket = "link delete"
+		case sxcuPasteBucket:
+			friendlyBucket = "paste"
+		case sxcuPasteDeleteBucket:
+			friendlyBucket = "paste delete"
+		case sxcuGlobalBucket:
+			friendlyBucket = "global"
+		}
+		endTime := timeNow().Add(time.Duration(waitMs) * time.Millisecond)
+		for ctx.Err() == nil {
+			remaining := endTime.Sub(timeNow())
+			if remaining <= 0 {
+				break
+			}
+			secs := int(remaining.Seconds())
+			if secs >= 60 {
+				rateLimitStatus = fmt.Sprintf("⏳ Rate limited (%s): %dm %ds remaining...", friendlyBucket, secs/60, secs%60)
+			} else {
+				rateLimitStatus = fmt.Sprintf("⏳ Rate limited (%s): %ds remaining...", friendlyBucket, secs)
+			}
+			updateOutput(buildOutput())
+			sleepDuration := 500 * time.Millisecond
+			if remaining < sleepDuration {
+				sleepDuration = remaining
+			}
+			if sleepContext(ctx, sleepDuration) != nil {
+				break
+			}
+		}
+		rateLimitStatus = ""
+	}
+
+	if err := job.SxcuOG.Validate(); err != nil && len(job.Files) > 0 {
+		return UploadSummary{Errors: []string{fmt.Sprintf("Embed: %v", err)}}
+	}
+
+	if subdomain, uploadToken := getSxcuSubdomain(); subdomain != "" && len(job.Files) > 0 && !job.SxcuPaste {
+		if _, err := validateSxcuSubdomain(ctx, subdomain, uploadToken); err != nil {
+			if ctx.Err() == nil {
+				errors = append(errors, fmt.Sprintf("Subdomain: %v", err))
+			}
+			return UploadSummary{Errors: errors, Cancelled: ctx.Err() != nil}
+		}
+	}
+
+	if job.SxcuCollectionID != "" && len(job.Files) > 0 {
+		coll, err := p.existingCollection(ctx, job)
+		if err != nil {
+			if ctx.Err() == nil {
+				errors = append(errors, fmt.Sprintf("Collection: %v", err))
+			}
+			return UploadSummary{Errors: errors, Cancelled: ctx.Err() != nil}
+		}
+		collection = *coll
+		collectionResult = "Collection: " + coll.URL
+		updateOutput(buildOutput())
+	} else if job.CreateCollection && len(job.Files) > 0 {
+		coll, err := p.CreateGroup(ctx, job, nil)
+		if err != nil {
+			if ctx.Err() == nil {
+				errors = append(errors, fmt.Sprintf("Collection creation: %v", err))
+			}
+		} else {
+			collection = *coll
+			collectionResult = "Collection: " + coll.URL
+		}
+		updateOutput(buildOutput())
+	}
+
+	for _, filePath := range job.Files {
+		if job.SxcuPaste && ctx.Err() == nil {
+			resp, err := uploadPasteFile(ctx, filePath, 5)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				errors = append(errors, fmt.Sprintf("%s: %v", filepath.Base(filePath), err))
+			} else {
+				results = append(results, resp.URL)
+				uploaded = append(uploaded, sxcuUploadResult(job, resp, filePath))
+			}
+			updateOutput(buildOutput())
+			continue
+		}
+		for ctx.Err() == nil {
+			check := rateLimits.sxcu(sxcuFileUploadBucket).check()
+			if check.Allowed {
+				break
+			}
+			waitWithCountdown(check.WaitMs, check.Bucket)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		opts := sxcuUploadOptions(job, &collection)
+		resp, err := uploadFileToSxcuWithRateLimitInfo(ctx, filePath, opts, 5, func(waitMs int64, bucket string) {
+			waitWithCountdown(waitMs, bucket)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			errors = append(errors, fmt.Sprintf("%s: %v", filepath.Base(filePath), err))
+		} else {
+			results = append(results, resp.URL)
+			uploaded = append(uploaded, sxcuUploadResult(job, resp, filePath))
+		}
+		updateOutput(buildOutput())
+	}
+
+	summary := UploadSummary{Results: results, GroupResult: collectionResult, Errors: errors, SuccessCount: len(results), Cancelled: ctx.Err() != nil, Items: uploaded}
+	if collection.ID != "" {
+		summary.Group = &collection
+	}
+	return summary
+}
+
+// shortenLinks creates an sxcu redirect for each URL. The items carry the
+// original URL as Source.
+func shortenLinks(ctx context.Context, urls []string) ([]*UploadResult, []string) {
+	var items []*UploadResult
+	var errors []string
+	for _, u := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+		resp, err := createSxcuLink(ctx, u, 5)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			errors = append(errors, fmt.Sprintf("%s: %v", u, err))
+			continue
+		}
+		items = append(items, &UploadResult{URL: resp.URL, ID: resp.ID, Source: u, DeleteURL: resp.DelURL})
+	}
+	return items, errors
+}
+
+type imgchestProvider struct{}
+
+func (imgchestProvider) Name() string { return "imgchest" }
+
+func (imgchestProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{
+		GroupLabel:  "Post",
+		Title:       true,
+		MaxFileSize: imgchestMaxFileSize,
+	}
+}
+
+func (imgchestProvider) UploadFile(ctx context.Context, job *UploadJob, filePath string, group *UploadGroup) (*UploadResult, error) {
+	if err := ValidateImgchestFile(filePath); err != nil {
+		return nil, err
+	}
+	var resp *ImgchestPostResponse
+	var err error
+	if group != nil {
+		resp, err = addToImgchestPost(ctx, group.ID, []string{filePath}, 3)
+	} else {
+		opts := job.Imgchest
+		opts.Title = job.Title
+		resp, err = uploadToImgchestBatch(ctx, []string{filePath}, opts, 3)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data.Images) == 0 {
+		return nil, fmt.Errorf("missing image in response")
+	}
+	img := resp.Data.Images[len(resp.Data.Images)-1]
+	return &UploadResult{URL: img.Link, ID: img.ID}, nil
+}
+
+func (imgchestProvider) UploadURL(ctx context.Context, job *UploadJob, targetURL string) (*UploadResult, error) {
+	return nil, errUnsupported
+}
+
+func (imgchestProvider) CreateGroup(ctx context.Context, job *UploadJob, items []*UploadResult) (*UploadGroup, error) {
+	return nil, fmt.Errorf("imgchest posts are created by uploading files: %w", errUnsupported)
+}
+
+func (imgchestProvider) DeleteUploads(ctx context.Context, entries []HistoryEntry) ([]HistoryEntry, error) {
+	deleted := make([]HistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		if err := deleteImgchestFile(ctx, firstNonEmpty(e.ItemID, e.URL)); err != nil {
+			return deleted, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		deleted = append(deleted, e)
+	}
+	return deleted, nil
+}
+
+func (imgchestProvider) Upload(ctx context.Context, job *UploadJob, updateOutput func(string)) UploadSummary {
+	if len(job.Files) == 0 {
+		return UploadSummary{}
+	}
+
+	opts := job.Imgchest
+	opts.Title = job.Title
+	postID := job.PostID
+
+	validFiles := make([]string, 0, len(job.Files))
+	errors := make([]string, 0, 4)
+
+	for _, filePath := range job.Files {
+		if err := ValidateImgchestFile(filePath); err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", filepath.Base(filePath), err))
+		} else {
+			validFiles = append(validFiles, filePath)
+		}
+	}
+
+	if len(validFiles) == 0 {
+		return UploadSummary{Errors: errors}
+	}
+
+	totalFiles := len(validFiles)
+	results := make([]string, 0, totalFiles)
+	uploaded := make([]*UploadResult, 0, totalFiles)
+	var postResult string
+	var post *UploadGroup
+	allImageIDs := make([]string, 0, totalFiles)
+
+	uploadedCount := 0
+	useUploadedCount := false
+
+	buildOutput := func() string {
+		var output strings.Builder
+		output.Grow(2048)
+		var successCount int
+		if useUploadedCount {
+			successCount = uploadedCount
+		} else {
+			successCount = len(results)
+		}
+		failCount := len(errors)
+		if failCount > 0 {
+			output.WriteString(fmt.Sprintf("Uploading... %d/%d (%d failed)\r\n\r\n", successCount, totalFiles, failCount))
+		} else {
+			output.WriteString(fmt.Sprintf("Uploading... %d/%d\r\n\r\n", successCount, totalFiles))
+		}
+		if postResult != "" {
+			output.WriteString(postResult)
+			output.WriteString("\r\n")
+		}
+		for _, r := range results {
+			output.WriteString(r)
+			output.WriteString("\r\n")
+		}
+		for _, e := range errors {
+			output.WriteString("Error: ")
+			output.WriteString(e)
+			output.WriteString("\r\n")
+		}
+		return output.String()
+	}
+
+	if postID != "" {
+		const batchSize = 20
+		totalBatches := (len(validFiles) + batchSize - 1) / batchSize
+		seenLinks := make(map[string]struct{}, totalFiles)
+		useUploadedCount = true
+
+		for batchNum := 1; batchNum <= totalBatches; batchNum++ {
+			if ctx.Err() != nil {
+				break
+			}
+			start := (batchNum - 1) * batchSize
+			end := start + batchSize
+			if end > len(validFiles) {
+				end = len(validFiles)
+			}
+			batch := validFiles[start:end]
+
+			resp, err := addToImgchestPost(ctx, postID, batch, 3)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				errors = append(errors, fmt.Sprintf("Batch %d: %s", batchNum, err.Error()))
+			} else {
+				if postResult == "" {
+					postResult = "Post: " + resp.GetPostURL()
+					post = &UploadGroup{ID: postID, URL: resp.GetPostURL()}
+				}
+				uploadedCount += len(batch)
+				links := make([]string, 0, len(resp.Data.Images))
+				ids := make([]string, 0, len(resp.Data.Images))
+				for _, img := range resp.Data.Images {
+					links = append(links, img.Link)
+					ids = append(ids, img.ID)
+				}
+				uploaded = append(uploaded, imgchestBatchItems(batch, links, ids)...)
+				for _, img := range resp.Data.Images {
+					if _, seen := seenLinks[img.Link]; !seen {
+						seenLinks[img.Link] = struct{}{}
+						results = append(results, img.Link)
+						allImageIDs = append(allImageIDs, img.ID)
+					}
+				}
+			}
+			updateOutput(buildOutput())
+		}
+
+		if ctx.Err() == nil {
+			if err := updateImgchestPost(ctx, postID, opts, 3); err != nil && ctx.Err() == nil {
+				errors = append(errors, fmt.Sprintf("Failed to update post settings: %v", err))
+				updateOutput(buildOutput())
+			}
+		}
+		if err := applyImgchestDescriptions(ctx, job, uploaded, allImageIDs); err != nil {
+			errors = append(errors, err.Error())
+			updateOutput(buildOutput())
+		}
+
+		return UploadSummary{Results: results, GroupResult: postResult, Errors: errors, SuccessCount: uploadedCount, Cancelled: ctx.Err() != nil, Items: uploaded, Group: post}
+	}
+
+	seenLinks := make(map[string]struct{}, totalFiles)
+	callback := func(batchNum int, totalBatches int, batch []string, postURL string, imageLinks []string, imageIDs []string, err error) {
+		if err != nil {
+			if ctx.Err() == nil {
+				errors = append(errors, fmt.Sprintf("Batch %d: %s", batchNum, err.Error()))
+			}
+		} else {
+			if postResult == "" && postURL != "" {
+				postResult = "Post: " + postURL
+				post = &UploadGroup{ID: imgchestPostID(postURL), URL: postURL}
+			}
+			uploaded = append(uploaded, imgchestBatchItems(batch, imageLinks, imageIDs)...)
+			for i, link := range imageLinks {
+				if _, seen := seenLinks[link]; !seen {
+					seenLinks[link] = struct{}{}
+					results = append(results, link)
+					if i < len(imageIDs) {
+						allImageIDs = append(allImageIDs, imageIDs[i])
+					}
+				}
+			}
+		}
+		updateOutput(buildOutput())
+	}
+
+	uploadToImgchestWithCallback(ctx, validFiles, opts, 3, callback)
+
+	if err := applyImgchestDescriptions(ctx, job, uploaded, allImageIDs); err != nil {
+		errors = append(errors, err.Error())
+		updateOutput(buildOutput())
+	}
+
+	return UploadSummary{Results: results, GroupResult: postResult, Errors: errors, SuccessCount: len(results), Cancelled: ctx.Err() != nil, Items: uploaded, Group: post}
+}
+
+// imgchestDescriptions pairs the uploaded images with descriptions from the
+// job, falling back to sidecar .txt files next to each source file.
+func imgchestDescriptions(job *UploadJob, items []*UploadResult, imageIDs []string) []ImgchestFileDescription {
+	uploadedIDs := make(map[string]struct{}, len(imageIDs))
+	for _, id := range imageIDs {
+		uploadedIDs[id] = struct{}{}
+	}
+	files := make([]ImgchestFileDescription, 0, len(items))
+	for _, item := range items {
+		if _, ok := uploadedIDs[item.ID]; !ok || item.ID == "" {
+			continue
+		}
+		desc := strings.TrimSpace(job.ImageDescriptions[item.Source])
+		if desc == "" {
+			desc = imgchestSidecarDescription(item.Source)
+		}
+		if desc != "" {
+			files = append(files, ImgchestFileDescription{ID: item.ID, Description: desc})
+			delete(uploadedIDs, item.ID)
+		}
+	}
+	return files
+}
+
+func applyImgchestDescriptions(ctx context.Context, job *UploadJob, items []*UploadResult, imageIDs []string) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	files := imgchestDescriptions(job, items, imageIDs)
+	if len(files) == 0 {
+		return nil
+	}
+	if job.Imgchest.Anonymous {
+		return fmt.Errorf("image descriptions were skipped: anonymous posts cannot be edited")
+	}
+	if err := updateImgchestFileDescriptions(ctx, files, 3); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// imgchestBatchItems pairs a batch with its images. The add endpoint returns
+// every image in the post, so the batch is matched against the trailing ones.
+func imgchestBatchItems(batch, links, ids []string) []*UploadResult {
+	if len(links) > len(batch) {
+		skip := len(links) - len(batch)
+		links = links[skip:]
+		if len(ids) >= skip {
+			ids = ids[skip:]
+		}
+	}
+	items := make([]*UploadResult, 0, len(links))
+	for i, link := range links {
+		item := &UploadResult{URL: link, Source: batch[i]}
+		if i < len(ids) {
+			item.ID = ids[i]
+		}
+		items = append(items, item)
+	}
+	return items
+}